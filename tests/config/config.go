@@ -5,39 +5,191 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// Loader resolves the layered configuration. Layers are merged in this order,
+// each one overriding the previous:
+//
+//  1. defaults (setDefaults)
+//  2. env.yaml (FileName)
+//  3. env.<flow>.yaml (one per selected flow), then env.local.yaml
+//     (Overlays, optional, next to env.yaml)
+//  4. E2E_* environment variables (EnvPrefix), e.g. E2E_TIMEOUTS_HELM=5m,
+//     plus the envAliases shortcuts such as E2E_REUSE_CLUSTERS=1
 type Loader struct {
-	FileName string // default: env.yaml
+	FileName  string   // default: env.yaml
+	Overlays  []string // optional files merged over FileName, lowest precedence first
+	EnvPrefix string   // default: E2E (empty disables env overrides)
+	Dir       string   // where the search starts; default: cwd
 }
 
-func NewLoader() Loader {
-	return Loader{FileName: "env.yaml"}
+// NewLoader returns the default loader with one env.<flow>.yaml overlay per
+// flow, in that order. flows is the FLOW selection as the plan resolved it
+// (FLOW=all expanded, the cwd fallback applied), so the overlays always match
+// the flows being provisioned.
+func NewLoader(flows ...string) Loader {
+	l := Loader{FileName: "env.yaml", EnvPrefix: "E2E"}
+	for _, flow := range flows {
+		l.Overlays = append(l.Overlays, fmt.Sprintf("env.%s.yaml", flow))
+	}
+	l.Overlays = append(l.Overlays, "env.local.yaml")
+	return l
+}
+
+// Layer kinds recorded in Source.
+const (
+	LayerDefault = "default"
+	LayerFile    = "file"
+	LayerEnv     = "env"
+)
+
+// Source tells which layer supplied an effective config value.
+type Source struct {
+	Layer  string // LayerDefault, LayerFile or LayerEnv
+	Origin string // file path or env var name (empty for defaults)
+}
+
+func (s Source) String() string {
+	if s.Origin == "" {
+		return s.Layer
+	}
+	return s.Layer + " " + s.Origin
+}
+
+// Layers describes what was merged into a viper instance.
+type Layers struct {
+	Files   []string          // absolute paths actually read, lowest precedence first
+	Sources map[string]Source // viper key (lowercase) -> layer of its effective value
 }
 
 // LoadViper finds env.yaml and returns a configured viper instance (no global state).
+// The returned path is the base env.yaml; overlays and env vars are already merged.
 func (l Loader) LoadViper() (*viper.Viper, string, error) {
-	start, err := os.Getwd()
+	v, layers, err := l.LoadLayers()
+	if err != nil {
+		return nil, "", err
+	}
+	return v, layers.Files[0], nil
+}
+
+// LoadLayers finds env.yaml, merges the overlays and env vars on top of it and
+// records which layer supplied each key.
+func (l Loader) LoadLayers() (*viper.Viper, Layers, error) {
+	base, err := l.findBase()
 	if err != nil {
-		return nil, "", fmt.Errorf("getwd: %w", err)
+		return nil, Layers{}, err
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	setDefaults(v)
+
+	layers := Layers{Sources: map[string]Source{}}
+	for _, k := range v.AllKeys() {
+		layers.Sources[k] = Source{Layer: LayerDefault}
+	}
+
+	if err := mergeFile(v, base, &layers); err != nil {
+		return nil, Layers{}, err
+	}
+
+	dir := filepath.Dir(layers.Files[0])
+	for _, name := range l.Overlays {
+		overlay := filepath.Join(dir, name)
+		if !fileExists(overlay) {
+			continue
+		}
+		if err := mergeFile(v, overlay, &layers); err != nil {
+			return nil, Layers{}, err
+		}
+	}
+
+	l.applyEnv(v, &layers)
+	return v, layers, nil
+}
+
+func (l Loader) findBase() (string, error) {
+	start := l.Dir
+	if start == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("getwd: %w", err)
+		}
+		start = wd
 	}
 
 	// Prefer deterministic: repo root + /tests/env.yaml
 	if repoRoot, err := findRepoRoot(start); err == nil {
 		candidate := filepath.Join(repoRoot, l.FileName)
 		if fileExists(candidate) {
-			return readViper(candidate)
+			return candidate, nil
 		}
 	}
 
 	// Fallback: walk upwards from cwd until find env.yaml
-	path, err := findUpwards(start, l.FileName)
+	return findUpwards(start, l.FileName)
+}
+
+// mergeFile reads one yaml layer and deep-merges it into v.
+func mergeFile(v *viper.Viper, configPath string, layers *Layers) error {
+	fv, abs, err := readViper(configPath)
 	if err != nil {
-		return nil, "", err
+		return err
+	}
+	if err := v.MergeConfigMap(fv.AllSettings()); err != nil {
+		return fmt.Errorf("merge config %s: %w", abs, err)
+	}
+
+	layers.Files = append(layers.Files, abs)
+	for _, k := range fv.AllKeys() {
+		layers.Sources[k] = Source{Layer: LayerFile, Origin: abs}
+	}
+	return nil
+}
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// envAliases are shorter env var names (after the prefix) for frequently used keys.
+var envAliases = map[string]string{
+	"REUSE_CLUSTERS": "setup.reuseclusters",
+	"TEARDOWN":       "setup.teardown",
+}
+
+// envName maps a viper key to its env var, e.g.
+// clusters.cluster-a.kubecontext -> E2E_CLUSTERS_CLUSTER_A_KUBECONTEXT.
+func envName(prefix, key string) string {
+	return prefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// applyEnv overrides known keys (from files or defaults) with prefixed env vars.
+// Only keys that already exist can be targeted, since "_" is ambiguous.
+func (l Loader) applyEnv(v *viper.Viper, layers *Layers) {
+	if l.EnvPrefix == "" {
+		return
+	}
+
+	byEnv := map[string]string{}
+	for _, k := range v.AllKeys() {
+		byEnv[envName(l.EnvPrefix, k)] = k
+	}
+	for alias, k := range envAliases {
+		if v.IsSet(k) {
+			byEnv[l.EnvPrefix+"_"+alias] = k
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		name, val, _ := strings.Cut(kv, "=")
+		key, ok := byEnv[name]
+		if !ok {
+			continue
+		}
+		v.Set(key, val)
+		layers.Sources[key] = Source{Layer: LayerEnv, Origin: name}
 	}
-	return readViper(path)
 }
 
 func readViper(configPath string) (*viper.Viper, string, error) {
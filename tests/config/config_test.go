@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestLoadLayersPrecedence(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "env.yaml", `
+clusters:
+  cluster-a:
+    name: cluster-a
+    kubeContext: kind-cluster-a
+timeouts:
+  apply: 1m
+  helm: 1m
+`)
+	writeFile(t, dir, "env.aws_only.yaml", `
+timeouts:
+  apply: 3m
+  helm: 3m
+`)
+	writeFile(t, dir, "env.local.yaml", `
+timeouts:
+  helm: 4m
+`)
+	t.Setenv("E2E_CLUSTERS_CLUSTER_A_KUBECONTEXT", "my-ctx")
+
+	l := Loader{
+		FileName:  "env.yaml",
+		Overlays:  []string{"env.aws_only.yaml", "env.local.yaml", "env.missing.yaml"},
+		EnvPrefix: "E2E",
+		Dir:       dir,
+	}
+
+	v, layers, err := l.LoadLayers()
+	if err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+
+	if len(layers.Files) != 3 {
+		t.Fatalf("expected 3 files merged, got %v", layers.Files)
+	}
+
+	cases := []struct {
+		key        string
+		want       any
+		wantLayer  string
+		wantOrigin string
+	}{
+		{"timeouts.createcluster", "2m", LayerDefault, ""},
+		{"timeouts.apply", "3m", LayerFile, filepath.Join(dir, "env.aws_only.yaml")},
+		{"timeouts.helm", "4m", LayerFile, filepath.Join(dir, "env.local.yaml")},
+		{"clusters.cluster-a.name", "cluster-a", LayerFile, filepath.Join(dir, "env.yaml")},
+		{"clusters.cluster-a.kubecontext", "my-ctx", LayerEnv, "E2E_CLUSTERS_CLUSTER_A_KUBECONTEXT"},
+	}
+	for _, tc := range cases {
+		if got := v.GetString(tc.key); got != tc.want {
+			t.Errorf("%s: want=%v got=%v", tc.key, tc.want, got)
+		}
+		src := layers.Sources[tc.key]
+		if src.Layer != tc.wantLayer || src.Origin != tc.wantOrigin {
+			t.Errorf("%s: want source=%s %s got=%s", tc.key, tc.wantLayer, tc.wantOrigin, src)
+		}
+	}
+}
+
+func TestLoadLayersEnvDuration(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "env.yaml", "timeouts:\n  helm: 1m\n")
+	t.Setenv("E2E_TIMEOUTS_HELM", "5m")
+
+	v, _, err := Loader{FileName: "env.yaml", EnvPrefix: "E2E", Dir: dir}.LoadLayers()
+	if err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+
+	if got := v.GetDuration("timeouts.helm"); got != 5*time.Minute {
+		t.Fatalf("timeouts.helm: want=5m got=%s", got)
+	}
+}
+
+func TestLoadLayersEnvAlias(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "env.yaml", "timeouts:\n  helm: 1m\n")
+	t.Setenv("E2E_REUSE_CLUSTERS", "1")
+
+	v, layers, err := Loader{FileName: "env.yaml", EnvPrefix: "E2E", Dir: dir}.LoadLayers()
+	if err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+
+	if !v.GetBool("setup.reuseClusters") {
+		t.Fatalf("setup.reuseClusters: want=true got=false")
+	}
+	if src := layers.Sources["setup.reuseclusters"]; src.Origin != "E2E_REUSE_CLUSTERS" {
+		t.Fatalf("setup.reuseClusters: want source E2E_REUSE_CLUSTERS got=%s", src)
+	}
+}
+
+func TestNewLoaderOverlaysFollowFlows(t *testing.T) {
+	// FLOW não é lido aqui: os flows chegam já resolvidos (FLOW=all expandido)
+	t.Setenv("FLOW", "all")
+
+	got := NewLoader("aws_only", "event_flow").Overlays
+	want := []string{"env.aws_only.yaml", "env.event_flow.yaml", "env.local.yaml"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Overlays = %v, want %v", got, want)
+	}
+}
+
+func TestLoadEnvRepoConfigIsValid(t *testing.T) {
+	v, layers, err := Loader{FileName: "env.yaml", Dir: ".."}.LoadLayers()
+	if err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+
+	if _, err := LoadEnv(v, filepath.Dir(layers.Files[0])); err != nil {
+		t.Fatalf("tests/env.yaml is invalid: %v", err)
+	}
+}
+
+func TestLoadEnvAggregatesValidationErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "env.yaml", `
+clusters:
+  cluster-a:
+    name: Cluster_A
+    kubeContext: kind-cluster-a
+    kindConfig: infra/kind/missing.yaml
+components:
+  localstack:
+    kind: helm
+    chart: infra/helm/charts/localstack
+    release: localstack
+    namespace: ""
+  nats:
+    kind: manifest
+    chart: infra/helm/charts/nats
+    namespace: nats
+    manifest: nats.yaml
+    replicas: 3
+timeouts:
+  helm: 0s
+`)
+	writeFile(t, dir, "nats.yaml", "kind: List\n")
+
+	v, _, err := Loader{FileName: "env.yaml", Dir: dir}.LoadLayers()
+	if err != nil {
+		t.Fatalf("LoadLayers failed: %v", err)
+	}
+
+	_, err = LoadEnv(v, dir)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+
+	got := map[string]bool{}
+	for _, fe := range verr.Errors {
+		got[fe.Path] = true
+	}
+	for _, path := range []string{
+		"components.nats.replicas",
+		"components.nats.chart",
+		"clusters.cluster-a.name",
+		"clusters.cluster-a.kindConfig",
+		"components.localstack.chart",
+		"components.localstack.namespace",
+		"timeouts.helm",
+	} {
+		if !got[path] {
+			t.Errorf("expected a problem at %s, got:\n%v", path, err)
+		}
+	}
+	if len(verr.Errors) != 7 {
+		t.Errorf("expected 7 problems, got %d:\n%v", len(verr.Errors), err)
+	}
+}
+
+func TestWithDependencies(t *testing.T) {
+	catalog := map[string]ComponentConfig{
+		"localstack":    {},
+		"dynamodb-seed": {DependsOn: []string{"localstack"}},
+		"nats":          {},
+		"argo-events":   {DependsOn: []string{"nats"}},
+	}
+
+	got, err := WithDependencies(catalog, []string{"dynamodb-seed", "argo-events", "localstack"})
+	if err != nil {
+		t.Fatalf("WithDependencies failed: %v", err)
+	}
+
+	want := []string{"localstack", "dynamodb-seed", "nats", "argo-events"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("want=%v got=%v", want, got)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	catalog := map[string]ComponentConfig{
+		"a": {DependsOn: []string{"b"}},
+		"b": {DependsOn: []string{"c"}},
+		"c": {DependsOn: []string{"a"}},
+		"d": {DependsOn: []string{"a"}},
+		"e": {},
+	}
+
+	cycles := findCycles(catalog)
+	if len(cycles) != 1 {
+		t.Fatalf("expected exactly one cycle, got %v", cycles)
+	}
+	if got := cycles["a"].Error(); got != "dependency cycle: a -> b -> c -> a" {
+		t.Fatalf("unexpected cycle: %s", got)
+	}
+}
@@ -4,60 +4,193 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 )
 
 type Env struct {
-	Clusters map[string]struct {
-		Name       string `mapstructure:"name"`
-		KubeCtx    string `mapstructure:"kubeContext"`
-		KindConfig string `mapstructure:"kindConfig"`
-	} `mapstructure:"clusters"`
-
-	HelmApps map[string]struct {
-		Chart     string `mapstructure:"chart"`
-		Release   string `mapstructure:"release"`
-		Namespace string `mapstructure:"namespace"`
-	} `mapstructure:"helm"`
-
-	ContainerApps map[string]struct {
-		Namespace string `mapstructure:"namespace"`
-		Manifest  string `mapstructure:"manifest"`
-	} `mapstructure:"container"`
+	Clusters   map[string]ClusterConfig   `mapstructure:"clusters"`
+	Components map[string]ComponentConfig `mapstructure:"components"`
+
+	Setup struct {
+		Parallelism   int    `mapstructure:"parallelism"`   // components installed at once, per cluster
+		ReuseClusters bool   `mapstructure:"reuseClusters"` // keep existing kind clusters that match their kindConfig
+		Teardown      string `mapstructure:"teardown"`      // always | on-success | never
+	} `mapstructure:"setup"`
 
 	Timeouts struct {
 		CreateCluster time.Duration `mapstructure:"createCluster"`
 		Apply         time.Duration `mapstructure:"apply"`
 		Helm          time.Duration `mapstructure:"helm"`
+		Readiness     time.Duration `mapstructure:"readiness"` // per readiness check, unless it sets its own
 	} `mapstructure:"timeouts"`
 }
 
+// Teardown policies (setup.teardown). Only clusters created by the run are ever deleted.
+const (
+	TeardownAlways    = "always"
+	TeardownOnSuccess = "on-success" // keep the clusters of a failed run for debugging
+	TeardownNever     = "never"
+)
+
+// ClusterConfig describes one kind cluster (clusters.<key>).
+type ClusterConfig struct {
+	Name       string   `mapstructure:"name"`
+	KubeCtx    string   `mapstructure:"kubeContext"`
+	KindConfig string   `mapstructure:"kindConfig"` // relative to RepoRoot
+	Images     []string `mapstructure:"images"`     // loaded into the cluster before any component
+}
+
+// Component kinds.
+const (
+	KindHelm       = "helm"       // helm upgrade --install of Chart
+	KindManifest   = "manifest"   // kubectl apply of Manifest
+	KindJob        = "job"        // Manifest holding Jobs: deleted and re-applied so it runs on every setup
+	KindCrossplane = "crossplane" // like helm, then installs Packages and waits for them to be healthy
+	KindLocalStack = "localstack" // like helm, with Services enabled and checked on the health endpoint
+)
+
+// ComponentConfig is one entry of the component catalog (components.<name>).
+// Chart/Version/Release/Values/Set apply to kind=helm|crossplane|localstack,
+// Packages to kind=crossplane, Services to kind=localstack and
+// Manifest/ServerSide to kind=manifest|job.
+type ComponentConfig struct {
+	Kind       string           `mapstructure:"kind"`
+	Namespace  string           `mapstructure:"namespace"`
+	Chart      string           `mapstructure:"chart"`
+	Version    string           `mapstructure:"version"`
+	Release    string           `mapstructure:"release"`
+	Values     []string         `mapstructure:"values"`
+	Set        []string         `mapstructure:"set"`
+	Manifest   string           `mapstructure:"manifest"`
+	ServerSide bool             `mapstructure:"serverSide"` // kubectl apply --server-side (CRDs too large for client-side apply)
+	Images     []string         `mapstructure:"images"`
+	Packages   []PackageConfig  `mapstructure:"packages"`
+	Services   []string         `mapstructure:"services"` // LocalStack services (dynamodb, s3, sqs, ...)
+	Readiness  []ReadinessCheck `mapstructure:"readiness"`
+	DependsOn  []string         `mapstructure:"dependsOn"` // other components.<name>
+}
+
+// Crossplane package kinds.
+const (
+	PackageProvider      = "Provider"
+	PackageFunction      = "Function"
+	PackageConfiguration = "Configuration"
+)
+
+// PackageConfig is a Crossplane package installed by a kind=crossplane component.
+type PackageConfig struct {
+	Kind       string `mapstructure:"kind"` // Provider | Function | Configuration
+	Name       string `mapstructure:"name"`
+	Package    string `mapstructure:"package"`    // package image, kind-loaded when PullPolicy is Never
+	PullPolicy string `mapstructure:"pullPolicy"` // packagePullPolicy; default: Never (local image)
+}
+
+// HelmApp returns the helm install view of a kind=helm component.
+func (c ComponentConfig) HelmApp() HelmAppConfig {
+	return HelmAppConfig{
+		Chart:     c.Chart,
+		Version:   c.Version,
+		Release:   c.Release,
+		Namespace: c.Namespace,
+		Values:    c.Values,
+		Set:       c.Set,
+		Images:    c.Images,
+		Readiness: c.Readiness,
+		DependsOn: c.DependsOn,
+	}
+}
+
+// ManifestApp returns the kubectl apply view of a kind=manifest|job component.
+func (c ComponentConfig) ManifestApp() ManifestAppConfig {
+	return ManifestAppConfig{
+		Manifest:   c.Manifest,
+		ServerSide: c.ServerSide,
+		Namespace:  c.Namespace,
+		Images:     c.Images,
+		Readiness:  c.Readiness,
+		DependsOn:  c.DependsOn,
+	}
+}
+
+// Readiness check kinds.
+const (
+	ReadyDeployment  = "deployment"     // rollout status deployment/Name
+	ReadyStatefulSet = "statefulset"    // rollout status statefulset/Name
+	ReadyJob         = "job"            // job/Name Complete; Failed fails at once
+	ReadyCRD         = "crd"            // crd/Name condition=established
+	ReadyHTTP        = "http"           // GET URL returns 200
+	ReadyDynamoTable = "dynamodb-table" // Table is ACTIVE at Endpoint
+	ReadyLocalStack  = "localstack"     // every Services entry is available|running at URL (/_localstack/health)
+	ReadyCondition   = "condition"      // Name (kind/name, e.g. eventbus/default) reports condition=Condition
+)
+
+// ReadinessCheck is waited on after a component is installed, before m.Run().
+// Name applies to deployment|statefulset|job|crd|condition, URL to http|localstack,
+// Services to localstack, Condition to condition and Table/Endpoint/Region to
+// dynamodb-table.
+type ReadinessCheck struct {
+	Kind      string        `mapstructure:"kind"`
+	Name      string        `mapstructure:"name"`
+	Namespace string        `mapstructure:"namespace"` // default: the component namespace
+	URL       string        `mapstructure:"url"`       // reached from the host (kind port mapping)
+	Table     string        `mapstructure:"table"`
+	Endpoint  string        `mapstructure:"endpoint"`
+	Region    string        `mapstructure:"region"`    // default: sa-east-1
+	Services  []string      `mapstructure:"services"`  // default: the services of the kind=localstack component
+	Condition string        `mapstructure:"condition"` // e.g. Ready, Deployed
+	Timeout   time.Duration `mapstructure:"timeout"`   // default: timeouts.readiness
+}
+
+// HelmAppConfig describes a chart installed with helm upgrade --install.
+type HelmAppConfig struct {
+	Chart     string // relative to RepoRoot
+	Version   string // --version, for repo charts
+	Release   string
+	Namespace string
+	Values    []string // values files relative to RepoRoot
+	Set       []string // --set key=value (a list keeps key case intact)
+	Images    []string // pulled and kind-loaded before install
+	Readiness []ReadinessCheck
+	DependsOn []string
+}
+
+// ManifestAppConfig describes a manifest applied with kubectl.
+type ManifestAppConfig struct {
+	Manifest   string // relative to RepoRoot
+	ServerSide bool
+	Namespace  string
+	Images     []string
+	Readiness  []ReadinessCheck
+	DependsOn  []string
+}
+
 // LoadEnv reads config into Env, applies defaults and validates.
+// Unknown keys and every validation problem are returned together as a *ValidationError.
 func LoadEnv(v *viper.Viper, repoRoot string) (Env, error) {
 	var e Env
 
-	// Defaults
-	v.SetDefault("cluster.name", "cluster-a")
-	v.SetDefault("timeouts.createCluster", "2m")
-	v.SetDefault("timeouts.apply", "2m")
+	setDefaults(v)
 
-	// Unmarshal
-	if err := v.Unmarshal(&e); err != nil {
+	// Unmarshal, tracking keys that did not map to any field
+	var md mapstructure.Metadata
+	if err := v.Unmarshal(&e, func(c *mapstructure.DecoderConfig) { c.Metadata = &md }); err != nil {
 		return Env{}, fmt.Errorf("unmarshal env: %w", err)
 	}
 
-	if err := validateEnv(e); err != nil {
+	if err := validateEnv(e, repoRoot, md.Unused); err != nil {
 		return Env{}, err
 	}
 	return e, nil
 }
 
-func validateEnv(e Env) error {
-	if e.Timeouts.CreateCluster <= 0 {
-		return fmt.Errorf("timeouts.createCluster must be > 0 (got %s)", e.Timeouts.CreateCluster)
-	}
-	if e.Timeouts.Apply <= 0 {
-		return fmt.Errorf("timeouts.apply must be > 0 (got %s)", e.Timeouts.Apply)
-	}
-	return nil
+// setDefaults registers the lowest config layer. It is safe to call more than once.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("timeouts.createCluster", "2m")
+	v.SetDefault("timeouts.apply", "2m")
+	v.SetDefault("timeouts.helm", "5m")
+	v.SetDefault("timeouts.readiness", "2m")
+	v.SetDefault("setup.parallelism", 4)
+	v.SetDefault("setup.reuseClusters", false)
+	v.SetDefault("setup.teardown", TeardownOnSuccess)
 }
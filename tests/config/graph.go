@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CycleError reports a dependsOn loop, e.g. Path = [a b a].
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// WithDependencies returns names plus every component they transitively depend on,
+// ordered so that dependencies always come before their dependents. Components
+// that do not depend on each other keep the order in which they were requested.
+func WithDependencies(catalog map[string]ComponentConfig, names []string) ([]string, error) {
+	var (
+		out   []string
+		state = map[string]int{} // 0: new, 1: visiting, 2: done
+		path  []string
+	)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 2:
+			return nil
+		case 1:
+			start := slices.Index(path, name)
+			return &CycleError{Path: append(slices.Clone(path[start:]), name)}
+		}
+
+		c, ok := catalog[name]
+		if !ok {
+			return fmt.Errorf("component %q is not declared in env.yaml components", name)
+		}
+
+		state[name] = 1
+		path = append(path, name)
+		for _, d := range c.DependsOn {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = 2
+
+		out = append(out, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// findCycles returns every dependsOn loop in the catalog, once each, keyed by its
+// alphabetically first member and rotated to start there.
+func findCycles(catalog map[string]ComponentConfig) map[string]*CycleError {
+	cycles := map[string]*CycleError{}
+
+	for _, name := range sortedKeys(catalog) {
+		_, err := WithDependencies(catalog, []string{name})
+
+		var ce *CycleError
+		if !errors.As(err, &ce) {
+			continue
+		}
+
+		loop := ce.Path[:len(ce.Path)-1]
+		first := slices.Index(loop, slices.Min(loop))
+		loop = append(slices.Clone(loop[first:]), loop[:first]...)
+		if _, dup := cycles[loop[0]]; !dup {
+			cycles[loop[0]] = &CycleError{Path: append(loop, loop[0])}
+		}
+	}
+	return cycles
+}
@@ -4,18 +4,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Loaded struct {
 	RepoRoot   string
-	ConfigPath string
+	ConfigPath string   // base env.yaml
+	Files      []string // every file merged, lowest precedence first
 	Viper      *viper.Viper
 	Env        Env
+
+	// Sources maps each viper key (lowercase) to the layer of its effective value.
+	Sources map[string]Source
+}
+
+// SourceOf returns the layer that supplied key (e.g. "timeouts.helm").
+func (l Loaded) SourceOf(key string) (Source, bool) {
+	s, ok := l.Sources[strings.ToLower(key)]
+	return s, ok
 }
 
-func Load() (Loaded, error) {
+// Load reads the layered configuration for the selected flows (see NewLoader).
+func Load(flows ...string) (Loaded, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return Loaded{}, fmt.Errorf("getwd: %w", err)
@@ -26,8 +38,8 @@ func Load() (Loaded, error) {
 		return Loaded{}, fmt.Errorf("find repo root: %w", err)
 	}
 
-	loader := NewLoader()
-	v, cfgPath, err := loader.LoadViper()
+	loader := NewLoader(flows...)
+	v, layers, err := loader.LoadLayers()
 	if err != nil {
 		return Loaded{}, err
 	}
@@ -39,8 +51,10 @@ func Load() (Loaded, error) {
 
 	return Loaded{
 		RepoRoot:   filepath.Clean(repoRoot),
-		ConfigPath: cfgPath,
+		ConfigPath: layers.Files[0],
+		Files:      layers.Files,
 		Viper:      v,
 		Env:        env,
+		Sources:    layers.Sources,
 	}, nil
 }
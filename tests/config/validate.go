@@ -0,0 +1,323 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldError is a single problem in env.yaml, keyed by its YAML path
+// (e.g. components.localstack.namespace).
+type FieldError struct {
+	Path string
+	Msg  string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Msg
+}
+
+// ValidationError aggregates every problem found while loading env.yaml, so a
+// broken config is reported in one go instead of one fix-and-rerun at a time.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid env config (%d problems):", len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	out := make([]error, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		out = append(out, fe)
+	}
+	return out
+}
+
+// validator collects FieldErrors; paths are checked relative to repoRoot.
+type validator struct {
+	repoRoot string
+	errs     []FieldError
+}
+
+func (v *validator) addf(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+var dns1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// dnsLabel requires a non-empty RFC 1123 label (what k8s accepts for namespaces and helm for releases).
+func (v *validator) dnsLabel(path, value string) {
+	switch {
+	case value == "":
+		v.addf(path, "is required")
+	case len(value) > 63:
+		v.addf(path, "%q must be at most 63 characters", value)
+	case !dns1123Label.MatchString(value):
+		v.addf(path, "%q must be a DNS-1123 label (lowercase alphanumerics and '-')", value)
+	}
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(path, "is required")
+	}
+}
+
+// pathExists requires rel to exist under repoRoot; wantDir selects dir vs file.
+func (v *validator) pathExists(path, rel string, wantDir bool) {
+	if rel == "" {
+		v.addf(path, "is required")
+		return
+	}
+
+	abs := filepath.Join(v.repoRoot, rel)
+	st, err := os.Stat(abs)
+	switch {
+	case err != nil:
+		v.addf(path, "%s does not exist", abs)
+	case wantDir && !st.IsDir():
+		v.addf(path, "%s is not a directory", abs)
+	case !wantDir && st.IsDir():
+		v.addf(path, "%s is a directory, expected a file", abs)
+	}
+}
+
+func (v *validator) positive(path string, d time.Duration) {
+	if d <= 0 {
+		v.addf(path, "must be > 0 (got %s)", d)
+	}
+}
+
+// unknownKeys reports keys that did not map to any Env field. mapstructure
+// reports map entries as "container[nats].chart"; we turn that into a YAML path.
+func (v *validator) unknownKeys(unused []string) {
+	slices.Sort(unused)
+	for _, k := range unused {
+		k = strings.NewReplacer("[", ".", "]", "").Replace(k)
+		v.addf(k, "unknown key")
+	}
+}
+
+func (v *validator) images(path string, images []string) {
+	for i, img := range images {
+		if strings.TrimSpace(img) == "" {
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "image is empty")
+		}
+	}
+}
+
+func (v *validator) readiness(path string, checks []ReadinessCheck) {
+	for i, c := range checks {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch c.Kind {
+		case ReadyDeployment, ReadyStatefulSet, ReadyJob, ReadyCRD:
+			v.required(p+".name", c.Name)
+		case ReadyHTTP, ReadyLocalStack:
+			if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.addf(p+".url", "%q must be an absolute http(s) URL", c.URL)
+			}
+		case ReadyDynamoTable:
+			v.required(p+".table", c.Table)
+			v.required(p+".endpoint", c.Endpoint)
+		case ReadyCondition:
+			if kind, name, ok := strings.Cut(c.Name, "/"); !ok || kind == "" || name == "" {
+				v.addf(p+".name", "%q must be <kind>/<name>", c.Name)
+			}
+			v.required(p+".condition", c.Condition)
+		default:
+			v.addf(p+".kind", "%q must be one of %s", c.Kind, strings.Join([]string{
+				ReadyDeployment, ReadyStatefulSet, ReadyJob, ReadyCRD, ReadyHTTP, ReadyDynamoTable, ReadyLocalStack, ReadyCondition,
+			}, ", "))
+		}
+		if c.Namespace != "" {
+			v.dnsLabel(p+".namespace", c.Namespace)
+		}
+		if c.Timeout < 0 {
+			v.addf(p+".timeout", "must not be negative (got %s)", c.Timeout)
+		}
+	}
+}
+
+func (v *validator) packages(path string, pkgs []PackageConfig) {
+	for i, pkg := range pkgs {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch pkg.Kind {
+		case PackageProvider, PackageFunction, PackageConfiguration:
+		default:
+			v.addf(p+".kind", "%q must be one of %s, %s, %s", pkg.Kind, PackageProvider, PackageFunction, PackageConfiguration)
+		}
+		v.dnsLabel(p+".name", pkg.Name)
+		v.required(p+".package", pkg.Package)
+		switch pkg.PullPolicy {
+		case "", "Never", "IfNotPresent", "Always":
+		default:
+			v.addf(p+".pullPolicy", "%q must be one of Never, IfNotPresent, Always", pkg.PullPolicy)
+		}
+	}
+}
+
+var localstackService = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func (v *validator) services(path string, services []string) {
+	if len(services) == 0 {
+		v.addf(path, "list at least one LocalStack service (e.g. dynamodb)")
+	}
+	for i, svc := range services {
+		if !localstackService.MatchString(svc) {
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "%q is not a LocalStack service name (e.g. dynamodb, s3, sqs)", svc)
+		}
+	}
+}
+
+// dependsOn requires every dependency to be another declared component.
+func (v *validator) dependsOn(path, self string, deps []string, e Env) {
+	for i, d := range deps {
+		_, ok := e.Components[d]
+		switch {
+		case d == self:
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "component cannot depend on itself")
+		case !ok:
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "%q is not declared under components", d)
+		}
+	}
+}
+
+func (v *validator) component(p, name string, c ComponentConfig, e Env) {
+	v.dnsLabel(p+".namespace", c.Namespace)
+
+	switch c.Kind {
+	case KindHelm, KindCrossplane, KindLocalStack:
+		v.pathExists(p+".chart", c.Chart, true)
+		v.dnsLabel(p+".release", c.Release)
+		for i, f := range c.Values {
+			v.pathExists(fmt.Sprintf("%s.values[%d]", p, i), f, false)
+		}
+		for i, kv := range c.Set {
+			if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+				v.addf(fmt.Sprintf("%s.set[%d]", p, i), "%q must be key=value", kv)
+			}
+		}
+		if c.Manifest != "" {
+			v.addf(p+".manifest", "only valid for kind %s or %s", KindManifest, KindJob)
+		}
+		if c.ServerSide {
+			v.addf(p+".serverSide", "only valid for kind %s or %s", KindManifest, KindJob)
+		}
+		if c.Kind == KindCrossplane {
+			v.packages(p+".packages", c.Packages)
+		} else if len(c.Packages) > 0 {
+			v.addf(p+".packages", "only valid for kind %s", KindCrossplane)
+		}
+		if c.Kind == KindLocalStack {
+			v.services(p+".services", c.Services)
+			if !slices.ContainsFunc(c.Readiness, func(r ReadinessCheck) bool { return r.Kind == ReadyLocalStack }) {
+				v.addf(p+".readiness", "kind %s requires at least one readiness check of kind %s", KindLocalStack, ReadyLocalStack)
+			}
+		} else if len(c.Services) > 0 {
+			v.addf(p+".services", "only valid for kind %s", KindLocalStack)
+		}
+
+	case KindManifest, KindJob:
+		v.pathExists(p+".manifest", c.Manifest, false)
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"chart", c.Chart != ""},
+			{"version", c.Version != ""},
+			{"release", c.Release != ""},
+			{"values", len(c.Values) > 0},
+			{"set", len(c.Set) > 0},
+		} {
+			if f.set {
+				v.addf(p+"."+f.name, "only valid for kind %s, %s or %s", KindHelm, KindCrossplane, KindLocalStack)
+			}
+		}
+		if len(c.Packages) > 0 {
+			v.addf(p+".packages", "only valid for kind %s", KindCrossplane)
+		}
+		if len(c.Services) > 0 {
+			v.addf(p+".services", "only valid for kind %s", KindLocalStack)
+		}
+		if c.Kind == KindJob && !slices.ContainsFunc(c.Readiness, func(r ReadinessCheck) bool { return r.Kind == ReadyJob }) {
+			v.addf(p+".readiness", "kind %s requires at least one readiness check of kind %s", KindJob, ReadyJob)
+		}
+
+	default:
+		v.addf(p+".kind", "%q must be one of %s", c.Kind, strings.Join([]string{
+			KindHelm, KindManifest, KindJob, KindCrossplane, KindLocalStack,
+		}, ", "))
+	}
+
+	v.images(p+".images", c.Images)
+	v.readiness(p+".readiness", c.Readiness)
+	v.dependsOn(p+".dependsOn", name, c.DependsOn, e)
+}
+
+func validateEnv(e Env, repoRoot string, unused []string) error {
+	v := &validator{repoRoot: repoRoot}
+
+	v.unknownKeys(unused)
+
+	for _, key := range sortedKeys(e.Clusters) {
+		c := e.Clusters[key]
+		p := "clusters." + key
+		v.dnsLabel(p+".name", c.Name)
+		v.required(p+".kubeContext", c.KubeCtx)
+		v.pathExists(p+".kindConfig", c.KindConfig, false)
+		v.images(p+".images", c.Images)
+	}
+
+	for _, name := range sortedKeys(e.Components) {
+		v.component("components."+name, name, e.Components[name], e)
+	}
+	cycles := findCycles(e.Components)
+	for _, name := range sortedKeys(cycles) {
+		v.addf("components."+name+".dependsOn", "%v", cycles[name])
+	}
+
+	if e.Setup.Parallelism < 1 {
+		v.addf("setup.parallelism", "must be >= 1 (got %d)", e.Setup.Parallelism)
+	}
+	switch e.Setup.Teardown {
+	case TeardownAlways, TeardownOnSuccess, TeardownNever:
+	default:
+		v.addf("setup.teardown", "%q must be one of %s, %s, %s", e.Setup.Teardown, TeardownAlways, TeardownOnSuccess, TeardownNever)
+	}
+
+	v.positive("timeouts.createCluster", e.Timeouts.CreateCluster)
+	v.positive("timeouts.apply", e.Timeouts.Apply)
+	v.positive("timeouts.helm", e.Timeouts.Helm)
+	v.positive("timeouts.readiness", e.Timeouts.Readiness)
+
+	return v.err()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Setup phases reported in SetupError.
+const (
+	PhaseReuse     = "reuse-cluster"
+	PhaseCreate    = "create-cluster"
+	PhaseResolve   = "resolve-plan"
+	PhasePreload   = "preload-images"
+	PhaseInstall   = "install"
+	PhaseReadiness = "readiness"
+)
+
+// SetupError is a failure while provisioning a cluster. Component is empty for
+// cluster-level phases (create, reuse, plan resolution, cluster images).
+type SetupError struct {
+	Cluster   string
+	Component string
+	Phase     string
+	Cause     error
+}
+
+func (e *SetupError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.where(), e.Phase, e.Cause)
+}
+
+func (e *SetupError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *SetupError) where() string {
+	if e.Component == "" {
+		return e.Cluster
+	}
+	return e.Cluster + "/" + e.Component
+}
+
+func setupErr(cluster, component, phase string, cause error) error {
+	return &SetupError{Cluster: cluster, Component: component, Phase: phase, Cause: cause}
+}
+
+// SetupSummary renders every failure in err (a SetupError or a join of them),
+// one block per cluster/component with the command output indented below.
+func SetupSummary(err error) string {
+	errs := flatten(err)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "setup failed (%d problem(s)):", len(errs))
+	for _, e := range errs {
+		var se *SetupError
+		if errors.As(e, &se) {
+			component := se.Component
+			if component == "" {
+				component = "-"
+			}
+			fmt.Fprintf(&b, "\n  cluster=%s component=%s phase=%s", se.Cluster, component, se.Phase)
+			e = se.Cause
+		} else {
+			b.WriteString("\n  error")
+		}
+		// the cause carries the helm/kubectl stderr captured by utils.ExecWithResult
+		for _, line := range strings.Split(strings.TrimRight(e.Error(), "\n"), "\n") {
+			b.WriteString("\n      ")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// flatten expands errors.Join trees into their leaves.
+func flatten(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []error
+		for _, e := range j.Unwrap() {
+			out = append(out, flatten(e)...)
+		}
+		return out
+	}
+	if err == nil {
+		return nil
+	}
+	return []error{err}
+}
+
+// joinFailures joins the errors of parallel setups, dropping the cancellations
+// that the first real failure caused in the others.
+func joinFailures(errs []error) error {
+	var real []error
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			real = append(real, err)
+		}
+	}
+	if len(real) == 0 {
+		return errors.Join(errs...)
+	}
+	return errors.Join(real...)
+}
@@ -0,0 +1,32 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestJoinFailuresAndSummary(t *testing.T) {
+	failure := setupErr("cluster-a", "localstack", PhaseReadiness, errors.New("job/seed not ready\nBackoffLimitExceeded"))
+	cancelled := setupErr("cluster-b", "nats", PhaseInstall, context.Canceled)
+
+	err := joinFailures([]error{cancelled, failure})
+	if errs := flatten(err); len(errs) != 1 || errs[0] != failure {
+		t.Fatalf("joinFailures kept %v", errs)
+	}
+	// só cancelamentos (ex: Ctrl-C): nenhum é descartado
+	if errs := flatten(joinFailures([]error{cancelled})); len(errs) != 1 {
+		t.Fatalf("joinFailures dropped every error: %v", errs)
+	}
+
+	summary := SetupSummary(errors.Join(failure, setupErr("cluster-b", "", PhaseCreate, errors.New("kind failed"))))
+	want := `setup failed (2 problem(s)):
+  cluster=cluster-a component=localstack phase=readiness
+      job/seed not ready
+      BackoffLimitExceeded
+  cluster=cluster-b component=- phase=create-cluster
+      kind failed`
+	if summary != want {
+		t.Fatalf("SetupSummary =\n%s\nwant\n%s", summary, want)
+	}
+}
@@ -0,0 +1,12 @@
+package aws_only
+
+import "tests/system/spec"
+
+// Plan: o que o flow precisa em cada cluster.
+var Plan = spec.Plan{
+	"cluster-a": {Components: []string{"localstack", "dynamodb-seed"}},
+}
+
+func init() {
+	spec.Register("aws_only", Plan)
+}
@@ -2,9 +2,12 @@ package event_flow
 
 import "tests/system/spec"
 
-var Infra = spec.InfraSpec{
-	Localstack: true,
-	DynamoSeed: true,
-	NATS:       true,
-	Redis:      true,
+// Plan: o que o flow precisa em cada cluster.
+var Plan = spec.Plan{
+	"cluster-a": {Components: []string{"localstack", "dynamodb-seed"}},
+	"cluster-b": {Components: []string{"nats", "redis", "argo-events", "argo-events-eventbus"}},
+}
+
+func init() {
+	spec.Register("event_flow", Plan)
 }
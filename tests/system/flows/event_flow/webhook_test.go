@@ -0,0 +1,36 @@
+package event_flow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"tests/utils"
+	"time"
+)
+
+func TestOrderWebhookTriggersSensor(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Minute)
+	t.Cleanup(cancel)
+
+	kube := utils.Kubectl{Context: "kind-cluster-b"}
+	events := utils.ArgoEvents{Kube: kube}
+
+	if err := kube.ApplyFile(ctx, "fixtures/order-webhook.yaml"); err != nil {
+		t.Fatalf("apply fixtures: %v", err)
+	}
+	if err := events.WaitReady(ctx, []string{"order"}, []string{"order"}, 2*time.Minute); err != nil {
+		t.Fatalf("argo events not ready: %v", err)
+	}
+
+	orderID := fmt.Sprintf("o%d", time.Now().Unix())
+	created, err := events.FireAndWait(ctx,
+		utils.Webhook{EventSource: "order", Port: 12000, Endpoint: "/order", Body: fmt.Sprintf(`{"id":%q}`, orderID)},
+		"argo-events", "configmaps", "e2e.flow=event_flow,order-id="+orderID, time.Minute,
+	)
+	if err != nil {
+		t.Fatalf("order %s: %v", orderID, err)
+	}
+	t.Logf("order %s triggered %v", orderID, created)
+}
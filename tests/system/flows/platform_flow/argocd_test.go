@@ -0,0 +1,48 @@
+package platform_flow
+
+import (
+	"context"
+	"testing"
+	"tests/utils"
+	"time"
+)
+
+func TestArgoCDApplication(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	argo := utils.ArgoCD{Kube: utils.Kubectl{Context: "kind-cluster-b"}}
+
+	t.Cleanup(func() {
+		// o cluster pode ser reaproveitado: o git server não fica para trás
+		cctx, ccancel := context.WithTimeout(context.Background(), time.Minute)
+		defer ccancel()
+		if err := argo.StopGitRepo(cctx, "argocd-e2e"); err != nil {
+			t.Logf("stop git repo: %v", err)
+		}
+	})
+	repo, err := argo.ServeGitRepo(ctx, "argocd-e2e", "fixtures/argocd-app")
+	if err != nil {
+		t.Fatalf("serve git repo: %v", err)
+	}
+	if err := argo.CreateApplication(ctx, utils.AppSpec{Name: "argocd-e2e", RepoURL: repo, Namespace: "argocd-e2e"}); err != nil {
+		t.Fatalf("create application: %v", err)
+	}
+	t.Cleanup(func() {
+		// ctx pode já ter expirado aqui
+		cctx, ccancel := context.WithTimeout(context.Background(), time.Minute)
+		defer ccancel()
+		if err := argo.DeleteApplication(cctx, "argocd-e2e"); err != nil {
+			t.Logf("delete application: %v", err)
+		}
+	})
+
+	if err := argo.Sync(ctx, "argocd-e2e"); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := argo.WaitSyncedHealthy(ctx, "argocd-e2e", 3*time.Minute); err != nil {
+		t.Fatalf("application not Synced/Healthy: %v", err)
+	}
+}
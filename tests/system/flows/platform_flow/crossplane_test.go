@@ -0,0 +1,48 @@
+package platform_flow
+
+import (
+	"context"
+	"testing"
+	"tests/utils"
+	"time"
+)
+
+func TestCrossplaneComposite(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	kube := utils.Kubectl{Context: "kind-cluster-b"}
+	xp := utils.Crossplane{Kube: kube}
+	const ns = "crossplane-e2e"
+
+	if err := kube.ApplyFile(ctx, "fixtures/xr-definition.yaml"); err != nil {
+		t.Fatalf("apply XRD/Composition: %v", err)
+	}
+	if err := kube.WaitFor(ctx, "", "xrd/xappconfigs.e2e.example.org", "Established", time.Minute); err != nil {
+		t.Fatalf("XRD not established: %v", err)
+	}
+	if err := kube.EnsureNamespace(ctx, ns); err != nil {
+		t.Fatalf("namespace: %v", err)
+	}
+
+	if err := xp.ApplyAndWaitReady(ctx, "fixtures/xr-appconfig.yaml", ns, "xappconfig.e2e.example.org/app", 3*time.Minute); err != nil {
+		t.Fatalf("XAppConfig not ready: %v", err)
+	}
+
+	var cms struct {
+		Items []struct {
+			Data map[string]string `json:"data"`
+		} `json:"items"`
+	}
+	if err := kube.GetJSON(ctx, ns, &cms, "configmap", "-l", "crossplane.io/composite=app"); err != nil {
+		t.Fatalf("get composed ConfigMap: %v", err)
+	}
+	if len(cms.Items) != 1 {
+		t.Fatalf("composed ConfigMaps = %d, want 1", len(cms.Items))
+	}
+	if got := cms.Items[0].Data["message"]; got != "hello from crossplane" {
+		t.Fatalf("ConfigMap message = %q, want %q", got, "hello from crossplane")
+	}
+}
@@ -2,8 +2,11 @@ package platform_flow
 
 import "tests/system/spec"
 
-var Infra = spec.InfraSpec{
-	NATS:   true,
-	Redis:  true,
-	ArgoCD: true,
+// Plan: o que o flow precisa em cada cluster.
+var Plan = spec.Plan{
+	"cluster-b": {Components: []string{"nats", "redis", "argocd", "crossplane", "argo-workflows", "argo-rollouts"}},
+}
+
+func init() {
+	spec.Register("platform_flow", Plan)
 }
@@ -0,0 +1,63 @@
+package platform_flow
+
+import (
+	"context"
+	"testing"
+	"tests/utils"
+	"time"
+)
+
+func TestRolloutCanary(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	ro := utils.Rollouts{Kube: utils.Kubectl{Context: "kind-cluster-b"}, Namespace: "rollouts-e2e"}
+
+	if err := ro.Apply(ctx, "fixtures/rollout-canary.yaml"); err != nil {
+		t.Fatalf("apply rollout: %v", err)
+	}
+	if err := ro.WaitHealthy(ctx, "web", 2*time.Minute); err != nil {
+		t.Fatalf("initial revision: %v", err)
+	}
+
+	if err := ro.SetImage(ctx, "web", "web", "nginx:1.27-alpine"); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+	if err := ro.WaitPausedAtStep(ctx, "web", 1, 2*time.Minute); err != nil {
+		t.Fatalf("canary did not pause at 20%%: %v", err)
+	}
+
+	if err := ro.Promote(ctx, "web"); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if err := ro.WaitHealthy(ctx, "web", 2*time.Minute); err != nil {
+		t.Fatalf("canary not promoted: %v", err)
+	}
+}
+
+func TestRolloutAbortsOnFailedAnalysis(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	ro := utils.Rollouts{Kube: utils.Kubectl{Context: "kind-cluster-b"}, Namespace: "rollouts-e2e"}
+
+	if err := ro.Apply(ctx, "fixtures/rollout-failing-analysis.yaml"); err != nil {
+		t.Fatalf("apply rollout: %v", err)
+	}
+	if err := ro.WaitHealthy(ctx, "web-analysis", 2*time.Minute); err != nil {
+		t.Fatalf("initial revision: %v", err)
+	}
+
+	if err := ro.SetImage(ctx, "web-analysis", "web", "nginx:1.27-alpine"); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+	why, err := ro.WaitAborted(ctx, "web-analysis", 3*time.Minute)
+	if err != nil {
+		t.Fatalf("rollout was not aborted: %v", err)
+	}
+	t.Logf("aborted as expected:\n%s", why)
+}
@@ -0,0 +1,23 @@
+package platform_flow
+
+import (
+	"context"
+	"testing"
+	"tests/utils"
+	"time"
+)
+
+func TestHelloWorkflow(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(cancel)
+
+	argo := utils.ArgoWorkflows{Kube: utils.Kubectl{Context: "kind-cluster-b"}}
+
+	name, err := argo.SubmitAndWait(ctx, "fixtures/hello-workflow.yaml", 4*time.Minute)
+	if err != nil {
+		t.Fatalf("workflow %s did not succeed: %v", name, err)
+	}
+	t.Logf("workflow %s Succeeded", name)
+}
@@ -0,0 +1,49 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tests/utils"
+)
+
+// selectedFlows is set by TestMain from FLOW.
+var selectedFlows []string
+
+// runCtx is cancelled by TestMain on SIGINT/SIGTERM.
+var runCtx = context.Background()
+
+// TestFlows runs the test package of every selected flow against the clusters
+// TestMain provisioned, one subtest per flow, so each flow reports its own result.
+func TestFlows(t *testing.T) {
+	if testing.Short() {
+		t.Skip("-short: no clusters were provisioned")
+	}
+	for _, flow := range selectedFlows {
+		t.Run(flow, func(t *testing.T) {
+			dir := filepath.Join("flows", flow)
+			if _, err := os.Stat(dir); err != nil {
+				t.Fatalf("flow %s has no test directory: %v", flow, err)
+			}
+
+			args := []string{"test", "-count=1", "./" + filepath.ToSlash(dir) + "/..."}
+			if testing.Verbose() {
+				args = append(args, "-v")
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			defer context.AfterFunc(runCtx, cancel)()
+
+			res, err := utils.ExecWithResult(ctx, utils.CmdOptions{Env: map[string]string{"FLOW": flow}},
+				"go", args...,
+			)
+			if err != nil {
+				t.Fatalf("flow %s failed (exit=%d):\n%s%s", flow, res.ExitCode, res.Stdout, res.Stderr)
+			}
+			t.Logf("flow %s passed:\n%s", flow, res.Stdout)
+		})
+	}
+}
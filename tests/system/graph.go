@@ -0,0 +1,64 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"tests/system/spec"
+)
+
+// installDAG runs install for every component as soon as all of its dependsOn are
+// done, with at most limit installs in flight. components must be ordered
+// dependencies first (spec.InfraSpec.Resolve does that). The first failure
+// cancels everything still waiting or running.
+func installDAG(ctx context.Context, components []spec.Component, limit int, install func(context.Context, spec.Component) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	done := make(map[string]chan struct{}, len(components))
+	for _, c := range components {
+		done[c.Name] = make(chan struct{})
+	}
+	for _, c := range components {
+		for _, d := range c.DependsOn {
+			if _, ok := done[d]; !ok {
+				return fmt.Errorf("%s depends on %s, which is not part of the plan", c.Name, d)
+			}
+		}
+	}
+
+	// One goroutine per component so that waiting on dependencies never holds a
+	// slot; the semaphore only bounds the installs themselves.
+	sem := make(chan struct{}, limit)
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, c := range components {
+		g.Go(func() error {
+			for _, d := range c.DependsOn {
+				select {
+				case <-done[d]:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+
+			if err := install(ctx, c); err != nil {
+				return err
+			}
+			close(done[c.Name])
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
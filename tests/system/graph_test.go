@@ -0,0 +1,117 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tests/config"
+	"tests/system/spec"
+)
+
+func dependsOn(deps ...string) config.ComponentConfig {
+	return config.ComponentConfig{DependsOn: deps}
+}
+
+func TestInstallDAGOrder(t *testing.T) {
+	// ordem de Resolve: dependências primeiro
+	components := []spec.Component{
+		{Name: "nats"},
+		{Name: "redis"},
+		{Name: "argo-events", ComponentConfig: dependsOn("nats")},
+		{Name: "eventbus", ComponentConfig: dependsOn("argo-events", "nats")},
+	}
+
+	var mu sync.Mutex
+	var done []string
+	err := installDAG(context.Background(), components, 4, func(ctx context.Context, c spec.Component) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range c.DependsOn {
+			if !slices.Contains(done, d) {
+				t.Errorf("%s started before %s was installed", c.Name, d)
+			}
+		}
+		done = append(done, c.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("installDAG: %v", err)
+	}
+	if len(done) != len(components) {
+		t.Fatalf("installed %v, want every component", done)
+	}
+}
+
+func TestInstallDAGFailureStopsDependents(t *testing.T) {
+	components := []spec.Component{
+		{Name: "nats"},
+		{Name: "slow"},
+		{Name: "argo-events", ComponentConfig: dependsOn("nats")},
+	}
+	boom := errors.New("helm failed")
+
+	err := installDAG(context.Background(), components, 2, func(ctx context.Context, c spec.Component) error {
+		switch c.Name {
+		case "nats":
+			return boom
+		case "slow":
+			// cancelado pela falha de nats
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				t.Error("slow was not cancelled")
+				return nil
+			}
+		default:
+			t.Errorf("%s installed although its dependency failed", c.Name)
+			return nil
+		}
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want the nats failure", err)
+	}
+}
+
+func TestInstallDAGLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	components := []spec.Component{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	err := installDAG(context.Background(), components, 2, func(ctx context.Context, c spec.Component) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("installDAG: %v", err)
+	}
+	if peak != 2 {
+		t.Fatalf("%d installs ran at once, want 2", peak)
+	}
+}
+
+func TestInstallDAGUnknownDependency(t *testing.T) {
+	components := []spec.Component{{Name: "a", ComponentConfig: config.ComponentConfig{DependsOn: []string{"missing"}}}}
+
+	err := installDAG(context.Background(), components, 1, func(context.Context, spec.Component) error {
+		t.Fatal("install must not run")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "not part of the plan") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -1,60 +1,83 @@
 package system
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"tests/system/spec"
 )
 
-func resolvePlan() spec.Plan {
+// defaultFlow is used when FLOW is unset and the cwd does not name a flow.
+const defaultFlow = "aws_only"
+
+// allFlows selects every registered flow (FLOW=all).
+const allFlows = "all"
+
+// resolvePlan returns the selected flows and the union of their plans, so
+// FLOW=aws_only,event_flow provisions each cluster once for both.
+func resolvePlan() ([]string, spec.Plan, error) {
 	// 1) prioridade: variável de ambiente
-	if flow := os.Getenv("FLOW"); flow != "" {
-		return resolveFromFlow(flow)
+	flows, err := parseFlows(os.Getenv("FLOW"))
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// 2) fallback: inferir pelo diretório
-	return resolvePlanFromCWD()
-}
-
-func resolveFromFlow(flow string) spec.Plan {
-	switch flow {
+	if len(flows) == 0 {
+		flows = []string{flowFromCWD()}
+	}
 
-	case "aws_only":
-		return spec.Plan{
-			"cluster-a": {Localstack: true, DynamoSeed: true},
-			"cluster-b": {NATS: false, Redis: false},
+	plans := make([]spec.Plan, 0, len(flows))
+	for _, flow := range flows {
+		plan, err := spec.Lookup(flow)
+		if err != nil {
+			return nil, nil, err
 		}
+		plans = append(plans, plan)
+	}
 
-	case "event_flow":
-		return spec.Plan{
-			"cluster-a": {Localstack: true, DynamoSeed: true},
-			"cluster-b": {NATS: true, Redis: true},
-		}
+	plan, err := spec.Union(plans...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge plans of %v: %w", flows, err)
+	}
+	return flows, plan, nil
+}
 
-	case "platform_flow":
-		return spec.Plan{
-			"cluster-b": {NATS: true, Redis: true, ArgoCD: true},
+// parseFlows splits a comma-separated FLOW value, dropping blanks and duplicates.
+func parseFlows(value string) ([]string, error) {
+	var flows []string
+	for _, f := range strings.Split(value, ",") {
+		f = strings.TrimSpace(f)
+		switch {
+		case f == "":
+		case f == allFlows:
+			flows = spec.Flows()
+			if len(flows) == 0 {
+				return nil, fmt.Errorf("FLOW=%s but no flow is registered", allFlows)
+			}
+			return flows, nil
+		case !slices.Contains(flows, f):
+			flows = append(flows, f)
 		}
-
-	default:
-		panic("FLOW inválido: " + flow)
 	}
+	return flows, nil
 }
 
-func resolvePlanFromCWD() spec.Plan {
-	wd, _ := os.Getwd()
-
-	switch {
-	case strings.Contains(wd, "aws_only"):
-		return resolveFromFlow("aws_only")
-
-	case strings.Contains(wd, "event_flow"):
-		return resolveFromFlow("event_flow")
-
-	case strings.Contains(wd, "platform_flow"):
-		return resolveFromFlow("platform_flow")
+// flowFromCWD returns the last path element of the cwd that is a registered flow.
+func flowFromCWD() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return defaultFlow
+	}
 
-	default:
-		return resolveFromFlow("aws_only")
+	flows := spec.Flows()
+	parts := strings.Split(filepath.ToSlash(wd), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if slices.Contains(flows, parts[i]) {
+			return parts[i]
+		}
 	}
+	return defaultFlow
 }
@@ -0,0 +1,24 @@
+package system
+
+import (
+	"slices"
+	"testing"
+
+	"tests/system/spec"
+)
+
+func TestParseFlows(t *testing.T) {
+	got, err := parseFlows(" event_flow, aws_only ,event_flow,,")
+	if err != nil || !slices.Equal(got, []string{"event_flow", "aws_only"}) {
+		t.Fatalf("parseFlows = %v, %v", got, err)
+	}
+
+	got, err = parseFlows("aws_only,all")
+	if err != nil || !slices.Equal(got, spec.Flows()) {
+		t.Fatalf("parseFlows(all) = %v, %v; want %v", got, err, spec.Flows())
+	}
+
+	if got, err := parseFlows(""); err != nil || len(got) != 0 {
+		t.Fatalf("parseFlows(\"\") = %v, %v", got, err)
+	}
+}
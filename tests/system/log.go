@@ -0,0 +1,22 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// logger writes progress lines prefixed with what they refer to
+// (e.g. "[cluster-a localstack]"), so parallel setups stay readable.
+type logger struct {
+	prefix string
+}
+
+func newLogger(parts ...string) logger {
+	return logger{prefix: "[" + strings.Join(parts, " ") + "] "}
+}
+
+func (l logger) Printf(format string, args ...any) {
+	// one Fprintf per line: concurrent writers never split a line
+	fmt.Fprintf(os.Stderr, l.prefix+format+"\n", args...)
+}
@@ -2,78 +2,71 @@ package system
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 	"testing"
 
 	"tests/config"
-	"tests/utils"
+
+	// flows se registram em init (spec.Register)
+	_ "tests/system/flows/aws_only"
+	_ "tests/system/flows/event_flow"
+	_ "tests/system/flows/platform_flow"
 )
 
 func TestMain(m *testing.M) {
+	// go test -short ./system roda só os testes unitários do setup (FakeRunner),
+	// sem clusters
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+
 	ctx := context.Background()
 
-	loaded, err := config.Load()
+	// os comandos rodam no próprio grupo de processos e não recebem o Ctrl-C do terminal:
+	// o primeiro sinal cancela runCtx, que encerra helm/kubectl/kind/go test (SIGTERM,
+	// depois SIGKILL) e segue para o teardown; um segundo sinal mata o processo na hora
+	var stop context.CancelFunc
+	runCtx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(runCtx, stop)
+
+	// 1) resolver plano (o que vai em qual cluster)
+	flows, plan, err := resolvePlan()
 	if err != nil {
-		fmt.Errorf("error to load configs")
+		fmt.Fprintln(os.Stderr, "resolve flow:", err)
 		os.Exit(1)
 	}
+	selectedFlows = flows
+	fmt.Fprintf(os.Stderr, "flows %v: clusters %v\n", flows, slices.Sorted(maps.Keys(plan)))
 
-	env := loaded.Env
-
-	// 1) resolver plano (o que vai em qual cluster)
-	plan := resolvePlan()
-
-	// 2) criar só os clusters que serão usados nesse flow
-	for key, infra := range plan {
-		_ = infra // só pra mostrar que usamos plan
-
-		c, ok := env.Clusters[key]
-		if !ok {
-			fmt.Fprintln(os.Stderr, "cluster not found in env.yaml:", key)
-			os.Exit(1)
-		}
-
-		if _, err := utils.ExecWithResult(ctx, utils.CmdOptions{Timeout: env.Timeouts.CreateCluster},
-			"kind", "create", "cluster",
-			"--name", c.Name,
-			"--config", fmt.Sprintf("%s/%s", loaded.RepoRoot, c.KindConfig),
-		); err != nil {
-			fmt.Fprintln(os.Stderr, "kind create failed:", err)
-			os.Exit(1)
-		}
+	// os overlays env.<flow>.yaml seguem os flows já resolvidos (FLOW=all expandido)
+	loaded, err := config.Load(flows...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error to load configs:", err)
+		os.Exit(1)
 	}
 
-	// 3) SetupInfra por cluster-alvo
-	for key, infra := range plan {
-		c := env.Clusters[key]
+	teardown := loaded.Env.Setup.Teardown
 
-		target := ClusterTarget{
-			Key:        key,
-			Name:       c.Name,
-			KubeCtx:    c.KubeCtx,
-			KindConfig: c.KindConfig,
-		}
-
-		if err := SetupInfra(ctx, target, infra, env, loaded); err != nil {
-			fmt.Fprintln(os.Stderr, "setup infra failed:", err)
-
-			for key2 := range plan {
-				c2 := env.Clusters[key2]
-				_ = utils.Exec(ctx, "kind", "delete", "cluster", "--name", c2.Name)
-			}
-			os.Exit(1)
-		}
+	// 2) criar os clusters do plano e instalar a infra de cada um, em paralelo
+	prov, err := Provision(runCtx, plan, loaded)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, SetupSummary(err))
+		Teardown(ctx, teardown, prov.Created, false)
+		os.Exit(1)
 	}
 
-	// 4) Run tests
+	// 3) Run tests (TestFlows roda os testes de cada flow)
 	code := m.Run()
 
-	// 5) Teardown só dos clusters do plano
-	// for key := range plan {
-	// 	c := env.Clusters[key]
-	// 	_ = utils.Exec(ctx, "kind", "delete", "cluster", "--name", c.Name)
-	// }
+	// 4) Teardown só dos clusters criados por esta execução
+	Teardown(ctx, teardown, prov.Created, code == 0)
 
 	os.Exit(code)
 }
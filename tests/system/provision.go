@@ -0,0 +1,157 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"tests/config"
+	"tests/system/spec"
+	"tests/utils"
+)
+
+// clusterSet tracks the kind clusters this run created; it is shared by the
+// per-cluster goroutines.
+type clusterSet struct {
+	mu      sync.Mutex
+	targets []ClusterTarget
+}
+
+func (s *clusterSet) add(t ClusterTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = append(s.targets, t)
+}
+
+func (s *clusterSet) list() []ClusterTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.targets)
+}
+
+// Provisioned is what Provision set up.
+type Provisioned struct {
+	Targets []ClusterTarget // every cluster of the plan
+	Created []ClusterTarget // the ones this run created (not reused); only these are torn down
+}
+
+// Provision creates every cluster of plan and installs its components, one
+// goroutine per cluster. With setup.reuseClusters, a cluster that already exists
+// and matches its kind config is kept instead of created. The first failure
+// cancels the other clusters; the returned error joins one *SetupError per
+// failed cluster, and Created is filled in even then, so the caller can apply
+// its Teardown policy.
+func Provision(ctx context.Context, plan spec.Plan, loaded config.Loaded) (Provisioned, error) {
+	targets, err := planTargets(plan, loaded.Env)
+	if err != nil {
+		return Provisioned{}, err
+	}
+
+	var (
+		created clusterSet
+		mu      sync.Mutex
+		errs    []error
+	)
+	g, gctx := errgroup.WithContext(ctx)
+
+	for _, t := range targets {
+		g.Go(func() error {
+			err := provisionCluster(gctx, t, plan[t.Key], loaded, &created)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+			return err
+		})
+	}
+
+	_ = g.Wait() // every error is in errs
+	return Provisioned{Targets: targets, Created: created.list()}, joinFailures(errs)
+}
+
+// provisionCluster creates (or reuses) one cluster and installs its components.
+func provisionCluster(ctx context.Context, t ClusterTarget, infra spec.InfraSpec, loaded config.Loaded, created *clusterSet) error {
+	log := newLogger(t.Key)
+	start := time.Now()
+
+	reused := false
+	if loaded.Env.Setup.ReuseClusters {
+		var err error
+		if reused, err = reuseCluster(ctx, t, loaded, log); err != nil {
+			return setupErr(t.Key, "", PhaseReuse, err)
+		}
+	}
+
+	if !reused {
+		existed, err := (utils.Kind{Runner: runner}).ClusterExists(ctx, t.Name)
+		if err != nil {
+			return setupErr(t.Key, "", PhaseCreate, err)
+		}
+
+		log.Printf("creating kind cluster %s", t.Name)
+		err = createCluster(ctx, t, loaded)
+		// only a cluster that did not exist before is ours to tear down; a
+		// cancelled create can still leave a half-built one behind
+		if !existed && (err == nil || (ctx.Err() != nil && !utils.StderrContains(err, "already exist"))) {
+			created.add(t)
+		}
+		if err != nil {
+			return setupErr(t.Key, "", PhaseCreate, err)
+		}
+		if err := labelConfigHash(ctx, t, loaded); err != nil {
+			return setupErr(t.Key, "", PhaseCreate, fmt.Errorf("label nodes: %w", err))
+		}
+		log.Printf("cluster created in %s", time.Since(start).Round(time.Second))
+	}
+
+	if err := SetupInfra(ctx, t, infra, loaded.Env, loaded); err != nil {
+		return err
+	}
+	log.Printf("infra ready in %s", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// planTargets returns the plan's clusters in key order.
+func planTargets(plan spec.Plan, env config.Env) ([]ClusterTarget, error) {
+	keys := make([]string, 0, len(plan))
+	for key := range plan {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	targets := make([]ClusterTarget, 0, len(keys))
+	for _, key := range keys {
+		c, ok := env.Clusters[key]
+		if !ok {
+			return nil, fmt.Errorf("cluster not found in env.yaml: %s", key)
+		}
+		targets = append(targets, NewClusterTarget(key, c))
+	}
+	return targets, nil
+}
+
+func createCluster(ctx context.Context, t ClusterTarget, loaded config.Loaded) error {
+	kind := utils.Kind{Timeout: loaded.Env.Timeouts.CreateCluster, Runner: cmdRunner(t.Key, "")}
+	return kind.CreateCluster(ctx, t.Name, filepath.Join(loaded.RepoRoot, t.KindConfig))
+}
+
+// DeleteClusters deletes the given kind clusters concurrently, logging failures.
+func DeleteClusters(ctx context.Context, targets []ClusterTarget) {
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Go(func() {
+			log := newLogger(t.Key)
+			log.Printf("deleting kind cluster %s", t.Name)
+			if err := (utils.Kind{Runner: cmdRunner(t.Key, "")}).DeleteCluster(ctx, t.Name); err != nil {
+				log.Printf("kind delete failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+}
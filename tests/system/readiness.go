@@ -0,0 +1,77 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"tests/config"
+	"tests/utils"
+)
+
+// waitReady evaluates the readiness gates of one component, in order. Each check
+// gets its own timeout (check.timeout, else timeouts.readiness).
+func waitReady(ctx context.Context, target ClusterTarget, app, namespace string, checks []config.ReadinessCheck, env config.Env) error {
+	for _, c := range checks {
+		if c.Namespace == "" {
+			c.Namespace = namespace
+		}
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = env.Timeouts.Readiness
+		}
+
+		start := time.Now()
+		if err := checkReady(ctx, target, c, timeout); err != nil {
+			return setupErr(target.Key, app, PhaseReadiness,
+				fmt.Errorf("%s not ready after %s: %w", describeCheck(c), time.Since(start).Round(time.Second), err))
+		}
+	}
+	return nil
+}
+
+func checkReady(ctx context.Context, target ClusterTarget, c config.ReadinessCheck, timeout time.Duration) error {
+	kube := utils.Kubectl{Context: target.KubeCtx, Runner: runner}
+
+	switch c.Kind {
+	case config.ReadyDeployment, config.ReadyStatefulSet:
+		return kube.WaitRolloutStatus(ctx, c.Namespace, c.Kind+"/"+c.Name, timeout)
+	case config.ReadyJob:
+		return kube.WaitJobComplete(ctx, c.Namespace, c.Name, timeout)
+	case config.ReadyCRD:
+		return kube.WaitCRDEstablished(ctx, c.Name, timeout)
+	case config.ReadyCondition:
+		return kube.WaitFor(ctx, c.Namespace, c.Name, c.Condition, timeout)
+	case config.ReadyHTTP:
+		return utils.WaitHTTPStatus(ctx, c.URL, http.StatusOK, timeout)
+	case config.ReadyLocalStack:
+		return utils.WaitLocalStackHealthy(ctx, c.URL, c.Services, timeout)
+	case config.ReadyDynamoTable:
+		db, err := utils.NewDynamoDB(ctx, c.Region, c.Endpoint)
+		if err != nil {
+			return err
+		}
+		return db.WaitTableStatus(ctx, c.Table, types.TableStatusActive, timeout)
+	default:
+		return fmt.Errorf("unknown readiness kind %q", c.Kind)
+	}
+}
+
+// describeCheck names a check in errors, e.g. job/localstack-dynamodb-init.
+func describeCheck(c config.ReadinessCheck) string {
+	switch c.Kind {
+	case config.ReadyHTTP:
+		return "http " + c.URL
+	case config.ReadyLocalStack:
+		return fmt.Sprintf("localstack %v at %s", c.Services, c.URL)
+	case config.ReadyDynamoTable:
+		return fmt.Sprintf("dynamodb-table %s at %s", c.Table, c.Endpoint)
+	case config.ReadyCondition:
+		return fmt.Sprintf("%s condition=%s", c.Name, c.Condition)
+	default:
+		return c.Kind + "/" + c.Name
+	}
+}
@@ -0,0 +1,107 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.yaml.in/yaml/v3"
+
+	"tests/config"
+	"tests/utils"
+)
+
+// kindConfigHashLabel is set on every node at creation time, so a later run can
+// tell whether an existing cluster was built from the current kind config.
+const kindConfigHashLabel = "e2e-kind-config-hash"
+
+// kindConfigHash fingerprints a kind config file and counts the nodes it declares
+// (kind creates a single control-plane when nodes is omitted).
+func kindConfigHash(path string) (string, int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var cfg struct {
+		Nodes []any `yaml:"nodes"`
+	}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return "", 0, fmt.Errorf("parse kind config %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])[:16], max(len(cfg.Nodes), 1), nil
+}
+
+// reuseCluster reports whether t already exists and still matches its kind
+// config. A drifted cluster is deleted here, so the caller just creates it again.
+func reuseCluster(ctx context.Context, t ClusterTarget, loaded config.Loaded, log logger) (bool, error) {
+	kind := utils.Kind{Runner: runner}
+
+	exists, err := kind.ClusterExists(ctx, t.Name)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	hash, nodes, err := kindConfigHash(filepath.Join(loaded.RepoRoot, t.KindConfig))
+	if err != nil {
+		return false, err
+	}
+
+	drift, err := clusterDrift(ctx, t, hash, nodes)
+	if err != nil {
+		return false, err
+	}
+	if drift == "" {
+		log.Printf("reusing existing kind cluster %s (config %s)", t.Name, hash)
+		return true, nil
+	}
+
+	log.Printf("existing kind cluster %s drifted from %s (%s), recreating", t.Name, t.KindConfig, drift)
+	if err := kind.DeleteCluster(ctx, t.Name); err != nil {
+		return false, fmt.Errorf("delete drifted cluster: %w", err)
+	}
+	return false, nil
+}
+
+// clusterDrift returns why the running cluster does not match the kind config,
+// or "" when it does.
+func clusterDrift(ctx context.Context, t ClusterTarget, hash string, nodes int) (string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+
+	kube := utils.Kubectl{Context: t.KubeCtx, Runner: runner}
+	if err := kube.GetJSON(ctx, "", &list, "nodes"); err != nil {
+		return "", fmt.Errorf("list nodes: %w", err)
+	}
+
+	if len(list.Items) != nodes {
+		return fmt.Sprintf("%d nodes, config declares %d", len(list.Items), nodes), nil
+	}
+	for _, n := range list.Items {
+		if got := n.Metadata.Labels[kindConfigHashLabel]; got != hash {
+			return fmt.Sprintf("node %s has %s=%q, want %q", n.Metadata.Name, kindConfigHashLabel, got, hash), nil
+		}
+	}
+	return "", nil
+}
+
+// labelConfigHash records the kind config fingerprint on a freshly created cluster.
+func labelConfigHash(ctx context.Context, t ClusterTarget, loaded config.Loaded) error {
+	hash, _, err := kindConfigHash(filepath.Join(loaded.RepoRoot, t.KindConfig))
+	if err != nil {
+		return err
+	}
+	kube := utils.Kubectl{Context: t.KubeCtx, Runner: runner}
+	return kube.LabelNodes(ctx, kindConfigHashLabel, hash)
+}
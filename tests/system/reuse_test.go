@@ -0,0 +1,40 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKindConfigHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	three := write("three.yaml", "kind: Cluster\nnodes:\n  - role: control-plane\n  - role: worker\n  - role: worker\n")
+	single := write("single.yaml", "kind: Cluster\n")
+
+	h1, nodes, err := kindConfigHash(three)
+	if err != nil || nodes != 3 || len(h1) != 16 {
+		t.Fatalf("kindConfigHash(three) = %q, %d, %v", h1, nodes, err)
+	}
+	h2, nodes, err := kindConfigHash(single)
+	if err != nil || nodes != 1 {
+		t.Fatalf("kindConfigHash(single) = %q, %d, %v", h2, nodes, err)
+	}
+	if h1 == h2 {
+		t.Fatal("different configs have the same hash")
+	}
+	if again, _, _ := kindConfigHash(three); again != h1 {
+		t.Fatalf("hash is not stable: %s != %s", again, h1)
+	}
+
+	if _, _, err := kindConfigHash(write("bad.yaml", "nodes: [")); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
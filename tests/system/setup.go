@@ -2,17 +2,35 @@ package system
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"tests/config"
 	"tests/system/spec"
 	"tests/utils"
+	"time"
 )
 
+// runner runs every kind/kubectl/helm/docker call made during setup and teardown.
+var runner utils.Runner = utils.ExecRunner{}
+
+// cmdRunner streams the output of long-running commands (helm --wait, kubectl
+// apply, image pulls, kind create cluster) to stderr, prefixed like the logger
+// plus the tool: "[cluster-a helm localstack]". Polling calls use runner directly.
+func cmdRunner(cluster, component string) utils.Runner {
+	return utils.LogRunner{Runner: runner, Output: os.Stderr, Cluster: cluster, Component: component}
+}
+
 type ClusterTarget struct {
-	Key        string
-	Name       string
-	KubeCtx    string
-	KindConfig string
+	Key string
+	config.ClusterConfig
+}
+
+func NewClusterTarget(key string, c config.ClusterConfig) ClusterTarget {
+	return ClusterTarget{Key: key, ClusterConfig: c}
 }
 
 func SetupInfra(ctx context.Context, target ClusterTarget, infra spec.InfraSpec, env config.Env, loaded config.Loaded) error {
@@ -20,62 +38,196 @@ func SetupInfra(ctx context.Context, target ClusterTarget, infra spec.InfraSpec,
 	// kubectl --context target.KubeCtx
 	// helm --kube-context target.KubeCtx
 
-	if infra.Localstack {
-		hm := utils.Helm{
-			KubeContext: target.KubeCtx,
-			Timeout:     env.Timeouts.Helm,
-		}
+	components, err := infra.Resolve(env.Components)
+	if err != nil {
+		return setupErr(target.Key, "", PhaseResolve, err)
+	}
 
-		opts := utils.HelmInstallOpts{
-			Release:   env.HelmApps["localstack"].Release,
-			Chart:     fmt.Sprintf("%s/%s", loaded.RepoRoot, env.HelmApps["localstack"].Chart),
-			Namespace: env.HelmApps["localstack"].Namespace,
-			Wait:      true,
-			CreateNS:  true,
-		}
+	if err := preloadImages(ctx, target, "", target.Images); err != nil {
+		return setupErr(target.Key, "", PhasePreload, err)
+	}
 
-		if err := hm.UpgradeInstall(ctx, opts); err != nil {
-			fmt.Errorf("error to install helm chart")
+	err = installDAG(ctx, components, env.Setup.Parallelism, func(ctx context.Context, c spec.Component) error {
+		log := newLogger(target.Key, c.Name)
+		start := time.Now()
+
+		log.Printf("installing (%s)", c.Kind)
+		if err := InstallComponent(ctx, target, c, env, loaded.RepoRoot); err != nil {
+			log.Printf("failed after %s", time.Since(start).Round(time.Second))
+			return err
 		}
+		log.Printf("ready in %s", time.Since(start).Round(time.Second))
+		return nil
+	})
+
+	var se *SetupError
+	switch {
+	case err == nil || errors.As(err, &se):
+		return err
+	case ctx.Err() != nil:
+		return setupErr(target.Key, "", PhaseInstall, err)
+	default:
+		// dependsOn outside the plan
+		return setupErr(target.Key, "", PhaseResolve, err)
 	}
+}
 
-	if infra.DynamoSeed {
-		kube := utils.Kubectl{
-			Context: target.KubeCtx,
-			Timeout: env.Timeouts.Apply,
-		}
+// InstallComponent installs one catalog component on target according to its kind.
+func InstallComponent(ctx context.Context, target ClusterTarget, c spec.Component, env config.Env, repoRoot string) error {
+	switch c.Kind {
+	case config.KindHelm:
+		return InstallHelmApp(ctx, target, c.Name, c.HelmApp(), env, repoRoot)
+	case config.KindManifest:
+		return ApplyManifestApp(ctx, target, c.Name, c.ManifestApp(), env, repoRoot)
+	case config.KindJob:
+		return RunJobApp(ctx, target, c.Name, c.ManifestApp(), env, repoRoot)
+	case config.KindCrossplane:
+		return InstallCrossplane(ctx, target, c.Name, c.ComponentConfig, env, repoRoot)
+	case config.KindLocalStack:
+		return InstallLocalStack(ctx, target, c.Name, c.ComponentConfig, env, repoRoot)
+	default:
+		return setupErr(target.Key, c.Name, PhaseInstall, fmt.Errorf("unknown component kind %q", c.Kind))
+	}
+}
 
-		manifestFile := fmt.Sprintf("%s/%s", loaded.RepoRoot, env.ContainerApps["dynamodb"].Manifest)
+// InstallHelmApp installs one chart on target and waits for its readiness checks.
+func InstallHelmApp(ctx context.Context, target ClusterTarget, name string, app config.HelmAppConfig, env config.Env, repoRoot string) error {
+	if err := preloadImages(ctx, target, name, app.Images); err != nil {
+		return setupErr(target.Key, name, PhasePreload, err)
+	}
 
-		if err := kube.ApplyFile(ctx, manifestFile); err != nil {
-			fmt.Errorf("error to apply manifest")
-		}
+	hm := utils.Helm{
+		KubeContext: target.KubeCtx,
+		Timeout:     env.Timeouts.Helm,
+		Runner:      cmdRunner(target.Key, name),
+	}
+
+	opts := utils.HelmInstallOpts{
+		Release:   app.Release,
+		Chart:     filepath.Join(repoRoot, app.Chart),
+		Version:   app.Version,
+		Namespace: app.Namespace,
+		Set:       app.Set,
+		Wait:      true,
+		CreateNS:  true,
+	}
+	for _, vf := range app.Values {
+		opts.Values = append(opts.Values, filepath.Join(repoRoot, vf))
+	}
+
+	if err := hm.UpgradeInstall(ctx, opts); err != nil {
+		return setupErr(target.Key, name, PhaseInstall, err)
+	}
+
+	return waitReady(ctx, target, name, app.Namespace, app.Readiness, env)
+}
+
+// ApplyManifestApp applies one manifest on target and waits for its readiness checks.
+func ApplyManifestApp(ctx context.Context, target ClusterTarget, name string, app config.ManifestAppConfig, env config.Env, repoRoot string) error {
+	if err := preloadImages(ctx, target, name, app.Images); err != nil {
+		return setupErr(target.Key, name, PhasePreload, err)
+	}
+
+	kube := utils.Kubectl{
+		Context: target.KubeCtx,
+		Timeout: env.Timeouts.Apply,
+		Runner:  cmdRunner(target.Key, name),
+	}
+
+	if err := kube.EnsureNamespace(ctx, app.Namespace); err != nil {
+		return setupErr(target.Key, name, PhaseInstall, fmt.Errorf("ensure namespace %s: %w", app.Namespace, err))
+	}
+
+	err := kube.Apply(ctx, utils.ApplyOpts{
+		Path:       filepath.Join(repoRoot, app.Manifest),
+		Namespace:  app.Namespace,
+		ServerSide: app.ServerSide,
+	})
+	if err != nil {
+		return setupErr(target.Key, name, PhaseInstall, err)
+	}
+
+	return waitReady(ctx, target, name, app.Namespace, app.Readiness, env)
+}
+
+// RunJobApp re-runs the Jobs in a manifest: Job specs are immutable, so they are
+// deleted before being applied again (e.g. on a reused cluster).
+func RunJobApp(ctx context.Context, target ClusterTarget, name string, app config.ManifestAppConfig, env config.Env, repoRoot string) error {
+	kube := utils.Kubectl{
+		Context: target.KubeCtx,
+		Timeout: env.Timeouts.Apply,
+		Runner:  cmdRunner(target.Key, name),
 	}
 
-	if infra.NATS {
-		// TODO: docker pull + kind load + kubectl apply nats.yaml
+	if err := kube.DeleteFile(ctx, filepath.Join(repoRoot, app.Manifest)); err != nil {
+		return setupErr(target.Key, name, PhaseInstall, fmt.Errorf("delete previous job: %w", err))
 	}
 
-	if infra.Redis {
-		// TODO: futuro
+	return ApplyManifestApp(ctx, target, name, app, env, repoRoot)
+}
+
+// InstallCrossplane installs the Crossplane chart, then its packages, and waits
+// for every package to be Installed and Healthy.
+func InstallCrossplane(ctx context.Context, target ClusterTarget, name string, c config.ComponentConfig, env config.Env, repoRoot string) error {
+	if err := InstallHelmApp(ctx, target, name, c.HelmApp(), env, repoRoot); err != nil {
+		return err
 	}
 
-	if infra.ArgoCD {
-		// TODO: futuro
+	xp := utils.Crossplane{Kube: utils.Kubectl{Context: target.KubeCtx, Timeout: env.Timeouts.Apply, Runner: runner}}
+	for _, p := range c.Packages {
+		if p.PullPolicy == "" || p.PullPolicy == "Never" {
+			if err := preloadImages(ctx, target, name, []string{p.Package}); err != nil {
+				return setupErr(target.Key, name, PhasePreload, err)
+			}
+		}
+		err := xp.ApplyPackage(ctx, utils.XPackage{Kind: p.Kind, Name: p.Name, Package: p.Package, PullPolicy: p.PullPolicy})
+		if err != nil {
+			return setupErr(target.Key, name, PhaseInstall, err)
+		}
 	}
 
+	for _, p := range c.Packages {
+		if err := xp.WaitPackageHealthy(ctx, p.Kind, p.Name, env.Timeouts.Readiness); err != nil {
+			return setupErr(target.Key, name, PhaseReadiness, err)
+		}
+	}
+	return nil
+}
+
+// InstallLocalStack installs the LocalStack chart with only c.Services enabled
+// (chart value startServices) and waits for them on the health endpoint.
+func InstallLocalStack(ctx context.Context, target ClusterTarget, name string, c config.ComponentConfig, env config.Env, repoRoot string) error {
+	app := c.HelmApp()
+	// --set splits on commas, so the list separator is escaped
+	app.Set = append(slices.Clone(app.Set), "startServices="+strings.Join(c.Services, `\,`))
+
+	app.Readiness = slices.Clone(app.Readiness)
+	for i, r := range app.Readiness {
+		if r.Kind == config.ReadyLocalStack && len(r.Services) == 0 {
+			app.Readiness[i].Services = c.Services
+		}
+	}
+
+	return InstallHelmApp(ctx, target, name, app, env, repoRoot)
+}
+
+func preloadImages(ctx context.Context, target ClusterTarget, component string, images []string) error {
+	d := utils.Docker{Runner: cmdRunner(target.Key, component)}
+	for _, img := range images {
+		if err := d.EnsurePulled(ctx, img); err != nil {
+			return fmt.Errorf("pull %s: %w", img, err)
+		}
+		if err := d.LoadIntoKind(ctx, target.Name, img); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func TargetsFromEnv(env config.Env) ([]ClusterTarget, error) {
 	var out []ClusterTarget
 	for key, c := range env.Clusters {
-		out = append(out, ClusterTarget{
-			Key:        key,
-			Name:       c.Name,
-			KubeCtx:    c.KubeCtx,
-			KindConfig: c.KindConfig,
-		})
+		out = append(out, NewClusterTarget(key, c))
 	}
 
 	return out, nil
@@ -0,0 +1,115 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tests/config"
+	"tests/system/spec"
+	"tests/utils"
+)
+
+// useFakeRunner swaps the package runner for a FakeRunner during the test.
+func useFakeRunner(t *testing.T) *utils.FakeRunner {
+	t.Helper()
+	r := &utils.FakeRunner{}
+	runner = r
+	t.Cleanup(func() { runner = utils.ExecRunner{} })
+	return r
+}
+
+func testEnv(components map[string]config.ComponentConfig) config.Env {
+	var env config.Env
+	env.Components = components
+	env.Setup.Parallelism = 2
+	env.Timeouts.Apply = time.Minute
+	env.Timeouts.Readiness = time.Minute
+	return env
+}
+
+func nsManifest(name string) string {
+	return fmt.Sprintf("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", name)
+}
+
+// expectManifestApp scripts the calls of ApplyManifestApp for a component with
+// one deployment readiness check named like the component.
+func expectManifestApp(r *utils.FakeRunner, name string) {
+	r.Expect("kubectl", "--context", "kind-test", "apply", "-f", "-").WithStdin(nsManifest(name))
+	r.Expect("kubectl", "--context", "kind-test", "-n", name, "apply", "-f", filepath.Join("/repo", name+".yaml"))
+	r.Expect("kubectl", "--context", "kind-test", "-n", name, "rollout", "status", "deployment/"+name, "--timeout", "1m0s")
+}
+
+func manifestComponent(name string, dependsOn ...string) config.ComponentConfig {
+	return config.ComponentConfig{
+		Kind:      config.KindManifest,
+		Namespace: name,
+		Manifest:  name + ".yaml",
+		Readiness: []config.ReadinessCheck{{Kind: config.ReadyDeployment, Name: name}},
+		DependsOn: dependsOn,
+	}
+}
+
+var testTarget = NewClusterTarget("cluster-test", config.ClusterConfig{Name: "test", KubeCtx: "kind-test"})
+
+func TestSetupInfraInstallsDependenciesFirst(t *testing.T) {
+	r := useFakeRunner(t)
+	expectManifestApp(r, "nats")
+	expectManifestApp(r, "events")
+
+	env := testEnv(map[string]config.ComponentConfig{
+		"nats":   manifestComponent("nats"),
+		"events": manifestComponent("events", "nats"),
+	})
+	err := SetupInfra(context.Background(), testTarget, spec.InfraSpec{Components: []string{"events"}}, env, config.Loaded{RepoRoot: "/repo"})
+	if err != nil {
+		t.Fatalf("SetupInfra: %v", err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// events só começa depois que o readiness de nats passou
+	var order []string
+	for _, c := range r.Calls {
+		order = append(order, c.String())
+	}
+	natsReady := slices.Index(order, "kubectl --context kind-test -n nats rollout status deployment/nats --timeout 1m0s")
+	eventsApply := slices.Index(order, "kubectl --context kind-test -n events apply -f "+filepath.Join("/repo", "events.yaml"))
+	if natsReady < 0 || eventsApply < natsReady {
+		t.Fatalf("events installed before nats was ready:\n%s", strings.Join(order, "\n"))
+	}
+}
+
+func TestSetupInfraReportsFailingComponent(t *testing.T) {
+	r := useFakeRunner(t)
+	r.Expect("kubectl", "--context", "kind-test", "apply", "-f", "-").WithStdin(nsManifest("nats"))
+	r.Expect("kubectl", "--context", "kind-test", "-n", "nats", "apply", "-f", filepath.Join("/repo", "nats.yaml")).
+		Returns("", "error: unable to recognize \"nats.yaml\"\n", 1)
+
+	env := testEnv(map[string]config.ComponentConfig{
+		"nats":   manifestComponent("nats"),
+		"events": manifestComponent("events", "nats"),
+	})
+	err := SetupInfra(context.Background(), testTarget, spec.InfraSpec{Components: []string{"events"}}, env, config.Loaded{RepoRoot: "/repo"})
+
+	var se *SetupError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *SetupError, got %T: %v", err, err)
+	}
+	if se.Cluster != "cluster-test" || se.Component != "nats" || se.Phase != PhaseInstall {
+		t.Fatalf("SetupError = %s/%s %s, want cluster-test/nats %s", se.Cluster, se.Component, se.Phase, PhaseInstall)
+	}
+	if !utils.StderrContains(err, "unable to recognize") {
+		t.Fatalf("the kubectl stderr was lost: %v", err)
+	}
+	// events nunca é instalado: qualquer chamada para ele seria inesperada
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
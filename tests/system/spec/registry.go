@@ -0,0 +1,59 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Plan{}
+)
+
+// Register makes a flow's plan available under name. Each flows/<name> package
+// calls it from init; registering the same name twice panics.
+func Register(name string, plan Plan) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("spec: Register with empty flow name")
+	}
+	if plan == nil {
+		panic("spec: Register plan is nil for flow " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("spec: Register called twice for flow " + name)
+	}
+	registry[name] = plan
+}
+
+// Lookup returns the plan registered under name.
+func Lookup(name string) (Plan, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	plan, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown flow %q (available flows: %s)", name, strings.Join(flowNames(), ", "))
+	}
+	return plan, nil
+}
+
+// Flows returns the registered flow names, sorted.
+func Flows() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return flowNames()
+}
+
+func flowNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
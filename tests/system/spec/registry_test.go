@@ -0,0 +1,37 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegisterTwicePanics(t *testing.T) {
+	Register("registry-test-dup", Plan{})
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "registry-test-dup") {
+			t.Fatalf("recover() = %v, want a panic naming the flow", r)
+		}
+	}()
+	Register("registry-test-dup", Plan{})
+}
+
+func TestLookupUnknownFlow(t *testing.T) {
+	Register("registry-test-known", Plan{})
+
+	_, err := Lookup("registry-test-missing")
+	if err == nil || !strings.Contains(err.Error(), "available flows") || !strings.Contains(err.Error(), "registry-test-known") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestFlowsSorted(t *testing.T) {
+	Register("registry-test-z", Plan{})
+	Register("registry-test-a", Plan{})
+
+	flows := Flows()
+	if !slices.IsSorted(flows) || !slices.Contains(flows, "registry-test-a") || !slices.Contains(flows, "registry-test-z") {
+		t.Fatalf("Flows() = %v", flows)
+	}
+}
@@ -1,12 +1,129 @@
 package spec
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"tests/config"
+)
+
+// InfraSpec lists the catalog components (env.yaml components.<name>) installed on one cluster.
 type InfraSpec struct {
-	Localstack bool
-	DynamoSeed bool
-	NATS       bool
-	Redis      bool
-	ArgoCD     bool
+	Components []string
+
+	// Overrides tweaks catalog entries for this flow only, keyed by component name.
+	Overrides map[string]Override
+}
+
+// Override is merged over a catalog entry: Namespace replaces when set, lists are appended.
+type Override struct {
+	Namespace string
+	Values    []string
+	Set       []string
+	Images    []string
+	Readiness []config.ReadinessCheck
+}
+
+// Component is a catalog entry with the flow overrides already applied.
+type Component struct {
+	Name string
+	config.ComponentConfig
 }
 
 // por cluster (target) -> InfraSpec
 type Plan map[string]InfraSpec
+
+// Resolve looks up every component in the catalog, adds the ones they depend on
+// and applies the overrides. The result is ordered dependencies first.
+func (s InfraSpec) Resolve(catalog map[string]config.ComponentConfig) ([]Component, error) {
+	for name := range s.Overrides {
+		if !slices.Contains(s.Components, name) {
+			return nil, fmt.Errorf("override for %q, which is not in the component list %v", name, s.Components)
+		}
+	}
+	for _, name := range s.Components {
+		if _, ok := catalog[name]; !ok {
+			return nil, fmt.Errorf("component %q is not declared in env.yaml components (available: %s)",
+				name, strings.Join(catalogNames(catalog), ", "))
+		}
+	}
+
+	names, err := config.WithDependencies(catalog, s.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Component, 0, len(names))
+	for _, name := range names {
+		c := catalog[name]
+		if o, ok := s.Overrides[name]; ok {
+			c = o.apply(c)
+		}
+		out = append(out, Component{Name: name, ComponentConfig: c})
+	}
+	return out, nil
+}
+
+func (o Override) apply(c config.ComponentConfig) config.ComponentConfig {
+	if o.Namespace != "" {
+		c.Namespace = o.Namespace
+	}
+	// slices.Concat copies, so the shared catalog entry is never mutated
+	c.Values = slices.Concat(c.Values, o.Values)
+	c.Set = slices.Concat(c.Set, o.Set)
+	c.Images = slices.Concat(c.Images, o.Images)
+	c.Readiness = slices.Concat(c.Readiness, o.Readiness)
+	return c
+}
+
+func catalogNames(catalog map[string]config.ComponentConfig) []string {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Union merges several flow plans so their clusters are provisioned once: per
+// cluster, every plan's components (first occurrence wins the order) and overrides.
+func Union(plans ...Plan) (Plan, error) {
+	out := Plan{}
+	for _, plan := range plans {
+		for cluster, infra := range plan {
+			merged := out[cluster]
+			for _, name := range infra.Components {
+				if !slices.Contains(merged.Components, name) {
+					merged.Components = append(merged.Components, name)
+				}
+			}
+			for name, o := range infra.Overrides {
+				if merged.Overrides == nil {
+					merged.Overrides = map[string]Override{}
+				}
+				m, err := merged.Overrides[name].merge(o)
+				if err != nil {
+					return nil, fmt.Errorf("%s/%s: %w", cluster, name, err)
+				}
+				merged.Overrides[name] = m
+			}
+			out[cluster] = merged
+		}
+	}
+	return out, nil
+}
+
+func (o Override) merge(other Override) (Override, error) {
+	if o.Namespace != "" && other.Namespace != "" && o.Namespace != other.Namespace {
+		return Override{}, fmt.Errorf("conflicting namespace overrides %q and %q", o.Namespace, other.Namespace)
+	}
+	if other.Namespace != "" {
+		o.Namespace = other.Namespace
+	}
+	o.Values = slices.Concat(o.Values, other.Values)
+	o.Set = slices.Concat(o.Set, other.Set)
+	o.Images = slices.Concat(o.Images, other.Images)
+	o.Readiness = slices.Concat(o.Readiness, other.Readiness)
+	return o, nil
+}
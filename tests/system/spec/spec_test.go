@@ -0,0 +1,50 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	a := Plan{
+		"cluster-a": {Components: []string{"localstack", "dynamodb-seed"}},
+		"cluster-b": {
+			Components: []string{"nats"},
+			Overrides:  map[string]Override{"nats": {Namespace: "nats", Set: []string{"a=1"}}},
+		},
+	}
+	b := Plan{
+		"cluster-b": {
+			Components: []string{"redis", "nats"},
+			Overrides:  map[string]Override{"nats": {Set: []string{"b=2"}}},
+		},
+	}
+
+	got, err := Union(a, b)
+	if err != nil {
+		t.Fatalf("Union: %v", err)
+	}
+	if c := got["cluster-a"].Components; !slices.Equal(c, []string{"localstack", "dynamodb-seed"}) {
+		t.Errorf("cluster-a components = %v", c)
+	}
+	if c := got["cluster-b"].Components; !slices.Equal(c, []string{"nats", "redis"}) {
+		t.Errorf("cluster-b components = %v", c)
+	}
+	o := got["cluster-b"].Overrides["nats"]
+	if o.Namespace != "nats" || !slices.Equal(o.Set, []string{"a=1", "b=2"}) {
+		t.Errorf("nats override = %+v", o)
+	}
+	// os planos de entrada não são alterados
+	if s := a["cluster-b"].Overrides["nats"].Set; !slices.Equal(s, []string{"a=1"}) {
+		t.Errorf("input override mutated: %v", s)
+	}
+
+	_, err = Union(a, Plan{"cluster-b": {
+		Components: []string{"nats"},
+		Overrides:  map[string]Override{"nats": {Namespace: "other"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "cluster-b/nats") {
+		t.Fatalf("conflicting namespaces: err = %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package system
+
+import (
+	"context"
+
+	"tests/config"
+)
+
+// Teardown applies the setup.teardown policy to the clusters this run created:
+// always deletes them, on-success only when the run passed, never keeps them.
+// Kept clusters are logged with the exact command that deletes them.
+func Teardown(ctx context.Context, policy string, created []ClusterTarget, success bool) {
+	remove := policy == config.TeardownAlways || (policy == config.TeardownOnSuccess && success)
+	if remove {
+		DeleteClusters(ctx, created)
+		return
+	}
+
+	for _, t := range created {
+		newLogger(t.Key).Printf("keeping kind cluster for debugging (teardown=%s, success=%t); delete it with: kind delete cluster --name %s",
+			policy, success, t.Name)
+	}
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ArgoEvents fires events into webhook EventSources and checks what the
+// Sensors triggered, through kubectl.
+type ArgoEvents struct {
+	Kube      Kubectl
+	Namespace string // where the EventSources/Sensors live; default: argo-events
+}
+
+func (a ArgoEvents) ns() string {
+	if a.Namespace == "" {
+		return "argo-events"
+	}
+	return a.Namespace
+}
+
+// curlImage runs the in-cluster webhook calls.
+const curlImage = "curlimages/curl:8.11.1"
+
+// WaitReady waits until the pods of the named EventSources and Sensors are Ready.
+// Applying them is not enough: an event fired before the sensor subscribed to
+// the EventBus is lost.
+func (a ArgoEvents) WaitReady(ctx context.Context, eventSources, sensors []string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	for _, es := range eventSources {
+		if err := a.waitPodsReady(ctx, "eventsource-name="+es, timeout); err != nil {
+			return fmt.Errorf("eventsource %s: %w", es, err)
+		}
+	}
+	for _, s := range sensors {
+		if err := a.waitPodsReady(ctx, "sensor-name="+s, timeout); err != nil {
+			return fmt.Errorf("sensor %s: %w", s, err)
+		}
+	}
+	return nil
+}
+
+func (a ArgoEvents) waitPodsReady(ctx context.Context, selector string, timeout time.Duration) error {
+	return poll(ctx, 2*time.Second, timeout, "pods "+selector+" Ready", func(ctx context.Context) (bool, error) {
+		var pods struct {
+			Items []struct {
+				Status struct {
+					Conditions []Condition `json:"conditions"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if err := a.Kube.GetJSON(ctx, a.ns(), &pods, "pods", "-l", selector); err != nil {
+			return false, err
+		}
+		ready := 0
+		for _, p := range pods.Items {
+			if conditionTrue(p.Status.Conditions, "Ready") {
+				ready++
+			}
+		}
+		if len(pods.Items) > 0 && ready == len(pods.Items) {
+			return true, nil
+		}
+		return false, fmt.Errorf("%d/%d ready", ready, len(pods.Items))
+	})
+}
+
+// Webhook is an event posted to a webhook EventSource.
+type Webhook struct {
+	EventSource string // EventSource name; its service is <name>-eventsource-svc
+	Port        int    // spec.webhook.<event>.port
+	Endpoint    string // spec.webhook.<event>.endpoint, e.g. /order
+	Body        string // JSON payload
+}
+
+// FireWebhook posts the event from a throwaway curl pod, since the EventSource
+// service is only reachable inside the cluster.
+func (a ArgoEvents) FireWebhook(ctx context.Context, ev Webhook) error {
+	url := fmt.Sprintf("http://%s-eventsource-svc.%s.svc.cluster.local:%d%s", ev.EventSource, a.ns(), ev.Port, ev.Endpoint)
+	pod := fmt.Sprintf("fire-%s-%d", ev.EventSource, time.Now().UnixNano()%100000)
+
+	_, err := a.Kube.RunOnce(ctx, a.ns(), pod, curlImage,
+		"-sS", "--fail",
+		"--retry", "10", "--retry-connrefused", "--retry-delay", "1",
+		"-X", "POST",
+		"-H", "Content-Type: application/json",
+		"-d", ev.Body,
+		url,
+	)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", url, err)
+	}
+	return nil
+}
+
+// WaitCreated waits until at least one kind object matching selector exists in
+// namespace (what a Sensor trigger creates) and returns their names.
+func (a ArgoEvents) WaitCreated(ctx context.Context, namespace, kind, selector string, timeout time.Duration) ([]string, error) {
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+
+	var names []string
+	what := fmt.Sprintf("%s -l %s in %s", kind, selector, namespace)
+	err := poll(ctx, time.Second, timeout, what, func(ctx context.Context) (bool, error) {
+		var list struct {
+			Items []struct {
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			} `json:"items"`
+		}
+		if err := a.Kube.GetJSON(ctx, namespace, &list, kind, "-l", selector); err != nil {
+			return false, err
+		}
+		if len(list.Items) == 0 {
+			return false, errors.New("sensor did not trigger")
+		}
+		for _, it := range list.Items {
+			names = append(names, it.Metadata.Name)
+		}
+		return true, nil
+	})
+	if err != nil && ctx.Err() == nil {
+		// only a timeout gets here: the sensor logs usually say why
+		return nil, fmt.Errorf("%w%s", err, a.sensorHint(ctx))
+	}
+	return names, err
+}
+
+// sensorHint returns the tail of every sensor pod log, which is where failed
+// triggers (RBAC, bad templates) are reported.
+func (a ArgoEvents) sensorHint(ctx context.Context) string {
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := a.Kube.GetJSON(ctx, a.ns(), &pods, "pods", "-l", "sensor-name"); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, p := range pods.Items {
+		logs, err := a.Kube.Logs(ctx, a.ns(), p.Metadata.Name, "", 20)
+		if err != nil {
+			continue
+		}
+		b.WriteString("\n--- sensor " + p.Metadata.Labels["sensor-name"] + " (" + p.Metadata.Name + ")")
+		for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+			b.WriteString("\n    " + line)
+		}
+	}
+	return b.String()
+}
+
+// FireAndWait fires ev and waits for the Sensor trigger to create a kind object
+// matching selector in namespace.
+func (a ArgoEvents) FireAndWait(ctx context.Context, ev Webhook, namespace, kind, selector string, timeout time.Duration) ([]string, error) {
+	if err := a.FireWebhook(ctx, ev); err != nil {
+		return nil, err
+	}
+	return a.WaitCreated(ctx, namespace, kind, selector, timeout)
+}
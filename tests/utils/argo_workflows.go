@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArgoWorkflows submits Workflows and waits for them through kubectl (no argo CLI needed).
+type ArgoWorkflows struct {
+	Kube      Kubectl
+	Namespace string // a controller.workflowNamespaces entry; default: argo
+}
+
+func (a ArgoWorkflows) ns() string {
+	if a.Namespace == "" {
+		return "argo"
+	}
+	return a.Namespace
+}
+
+// Workflow phases.
+const (
+	WorkflowSucceeded = "Succeeded"
+	WorkflowFailed    = "Failed"
+	WorkflowError     = "Error"
+)
+
+// Submit creates the Workflow in path (generateName is fine) and returns its name.
+func (a ArgoWorkflows) Submit(ctx context.Context, path string) (string, error) {
+	created, err := a.Kube.Create(ctx, a.ns(), path)
+	if err != nil {
+		return "", fmt.Errorf("submit %s: %w", path, err)
+	}
+	for _, obj := range created {
+		if kind, name, ok := strings.Cut(obj, "/"); ok && strings.HasPrefix(kind, "workflow.") {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("submit %s: no Workflow created (got %v)", path, created)
+}
+
+type workflowStatus struct {
+	Status struct {
+		Phase   string `json:"phase"`
+		Message string `json:"message"`
+		Nodes   map[string]struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+			Type        string `json:"type"`
+			Phase       string `json:"phase"`
+			Message     string `json:"message"`
+		} `json:"nodes"`
+	} `json:"status"`
+}
+
+// Wait polls the Workflow until it completes and returns its phase. A phase
+// other than Succeeded is an error that carries the logs of every failed node.
+func (a ArgoWorkflows) Wait(ctx context.Context, name string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	var phase string
+	err := poll(ctx, 2*time.Second, timeout, "workflow "+name, func(ctx context.Context) (bool, error) {
+		var wf workflowStatus
+		if err := a.Kube.GetJSON(ctx, a.ns(), &wf, "workflow", name); err != nil {
+			return false, err
+		}
+		phase = wf.Status.Phase
+		switch phase {
+		case WorkflowSucceeded:
+			return true, nil
+		case WorkflowFailed, WorkflowError:
+			return true, fmt.Errorf("workflow %s %s: %s\n%s", name, phase, wf.Status.Message, a.failedNodeLogs(ctx, name, wf))
+		}
+		return false, fmt.Errorf("phase=%q", phase)
+	})
+	return phase, err
+}
+
+// SubmitAndWait submits the Workflow in path and waits for it to succeed.
+func (a ArgoWorkflows) SubmitAndWait(ctx context.Context, path string, timeout time.Duration) (string, error) {
+	name, err := a.Submit(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	_, err = a.Wait(ctx, name, timeout)
+	return name, err
+}
+
+// nodeIDAnnotation ties a workflow pod to its node; pod names no longer embed
+// the node id (POD_NAMES=v2).
+const nodeIDAnnotation = "workflows.argoproj.io/node-id"
+
+// failedNodeLogs renders the message and main container logs of every failed
+// pod node. Problems fetching logs are reported inline, never returned.
+func (a ArgoWorkflows) failedNodeLogs(ctx context.Context, name string, wf workflowStatus) string {
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Name        string            `json:"name"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	podByNode := map[string]string{}
+	if err := a.Kube.GetJSON(ctx, a.ns(), &pods, "pods", "-l", "workflows.argoproj.io/workflow="+name); err == nil {
+		for _, p := range pods.Items {
+			podByNode[p.Metadata.Annotations[nodeIDAnnotation]] = p.Metadata.Name
+		}
+	}
+
+	var ids []string
+	for id, n := range wf.Status.Nodes {
+		if n.Type == "Pod" && (n.Phase == WorkflowFailed || n.Phase == WorkflowError) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	for _, id := range ids {
+		n := wf.Status.Nodes[id]
+		fmt.Fprintf(&b, "--- node %s (%s): %s\n", n.DisplayName, n.Phase, n.Message)
+
+		pod, ok := podByNode[id]
+		if !ok {
+			b.WriteString("    (pod not found)\n")
+			continue
+		}
+		logs, err := a.Kube.Logs(ctx, a.ns(), pod, "main", 50)
+		if err != nil {
+			fmt.Fprintf(&b, "    (logs of %s: %v)\n", pod, err)
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	return b.String()
+}
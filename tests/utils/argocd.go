@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArgoCD drives Argo CD Applications through kubectl (no argocd CLI needed).
+type ArgoCD struct {
+	Kube      Kubectl
+	Namespace string // where Argo CD runs; default: argocd
+}
+
+func (a ArgoCD) ns() string {
+	if a.Namespace == "" {
+		return "argocd"
+	}
+	return a.Namespace
+}
+
+// gitServerImage serves repos with git daemon; it must have sh and tar (kubectl cp).
+const gitServerImage = "alpine/git:2.47.2"
+
+// ServeGitRepo publishes the local directory dir as a git repository inside
+// the cluster (a git-<name> pod running git daemon in the Argo CD namespace)
+// and returns the URL Argo CD should use. Calling it again replaces the
+// content with a new commit on main. Use StopGitRepo to remove the server.
+func (a ArgoCD) ServeGitRepo(ctx context.Context, name, dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if st, err := os.Stat(abs); err != nil || !st.IsDir() {
+		return "", fmt.Errorf("git repo source %s is not a directory", abs)
+	}
+
+	pod := "git-" + name
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    app: %[1]s
+spec:
+  containers:
+    - name: git
+      image: %[3]s
+      command: ["sh", "-c", "mkdir -p /srv/git && exec git daemon --reuseaddr --export-all --base-path=/srv/git /srv/git"]
+      ports:
+        - name: git
+          containerPort: 9418
+      readinessProbe:
+        tcpSocket:
+          port: git
+        periodSeconds: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+    - name: git
+      port: 9418
+      targetPort: git
+`, pod, a.ns(), gitServerImage)
+
+	if err := a.Kube.ApplyYAML(ctx, manifest); err != nil {
+		return "", fmt.Errorf("git server %s: %w", pod, err)
+	}
+	if err := a.Kube.WaitFor(ctx, a.ns(), "pod/"+pod, "Ready", 2*time.Minute); err != nil {
+		return "", fmt.Errorf("git server %s: %w", pod, err)
+	}
+
+	if _, err := a.Kube.Exec(ctx, a.ns(), pod, "rm", "-rf", "/srv/src"); err != nil {
+		return "", err
+	}
+	if err := a.Kube.CopyTo(ctx, abs, a.ns(), pod, "/srv/src"); err != nil {
+		return "", fmt.Errorf("copy %s to %s: %w", abs, pod, err)
+	}
+	script := `set -e
+cd /srv/src
+rm -rf .git
+git init -q -b main
+git add -A
+git -c user.name=e2e -c user.email=e2e@local commit -q -m "e2e: $(date -u +%FT%TZ)"
+rm -rf /srv/git/repo.git
+git clone -q --bare /srv/src /srv/git/repo.git`
+	if _, err := a.Kube.Exec(ctx, a.ns(), pod, "sh", "-c", script); err != nil {
+		return "", fmt.Errorf("commit repo in %s: %w", pod, err)
+	}
+
+	return fmt.Sprintf("git://%s.%s.svc.cluster.local:9418/repo.git", pod, a.ns()), nil
+}
+
+// StopGitRepo deletes the git-<name> pod and service created by ServeGitRepo;
+// missing objects are not an error.
+func (a ArgoCD) StopGitRepo(ctx context.Context, name string) error {
+	pod := "git-" + name
+	if err := a.Kube.DeleteObject(ctx, a.ns(), "service/"+pod); err != nil {
+		return err
+	}
+	return a.Kube.DeleteObject(ctx, a.ns(), "pod/"+pod)
+}
+
+// AppSpec is the part of an Application the tests care about.
+type AppSpec struct {
+	Name      string
+	RepoURL   string // e.g. from ServeGitRepo
+	Path      string // directory inside the repo; default: .
+	Revision  string // default: HEAD
+	Namespace string // destination namespace (created by the sync)
+}
+
+// CreateApplication creates (or updates) an Application without automated
+// sync; use Sync to trigger it.
+func (a ArgoCD) CreateApplication(ctx context.Context, app AppSpec) error {
+	if app.Path == "" {
+		app.Path = "."
+	}
+	if app.Revision == "" {
+		app.Revision = "HEAD"
+	}
+
+	manifest := fmt.Sprintf(`apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  project: default
+  source:
+    repoURL: %s
+    path: %s
+    targetRevision: %s
+  destination:
+    server: https://kubernetes.default.svc
+    namespace: %s
+  syncPolicy:
+    syncOptions:
+      - CreateNamespace=true
+`, app.Name, a.ns(), app.RepoURL, app.Path, app.Revision, app.Namespace)
+
+	if err := a.Kube.ApplyYAML(ctx, manifest); err != nil {
+		return fmt.Errorf("create application %s: %w", app.Name, err)
+	}
+	return nil
+}
+
+// Sync starts a sync of the Application the same way the UI does: a hard
+// refresh followed by setting .operation.
+func (a ArgoCD) Sync(ctx context.Context, name string) error {
+	refresh := `{"metadata":{"annotations":{"argocd.argoproj.io/refresh":"hard"}}}`
+	if err := a.Kube.Patch(ctx, a.ns(), "application/"+name, "merge", refresh); err != nil {
+		return fmt.Errorf("refresh application %s: %w", name, err)
+	}
+
+	op := `{"operation":{"initiatedBy":{"username":"e2e"},"sync":{"syncStrategy":{"hook":{}}}}}`
+	if err := a.Kube.Patch(ctx, a.ns(), "application/"+name, "merge", op); err != nil {
+		return fmt.Errorf("sync application %s: %w", name, err)
+	}
+	return nil
+}
+
+type appStatus struct {
+	Operation *struct{} `json:"operation"`
+	Status    struct {
+		Sync struct {
+			Status   string `json:"status"`
+			Revision string `json:"revision"`
+		} `json:"sync"`
+		Health struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"health"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+		OperationState *struct {
+			Phase      string `json:"phase"`
+			Message    string `json:"message"`
+			SyncResult struct {
+				Resources []struct {
+					Kind      string `json:"kind"`
+					Namespace string `json:"namespace"`
+					Name      string `json:"name"`
+					Status    string `json:"status"`
+					Message   string `json:"message"`
+				} `json:"resources"`
+			} `json:"syncResult"`
+		} `json:"operationState"`
+		Resources []struct {
+			Kind      string `json:"kind"`
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+			Status    string `json:"status"`
+			Health    *struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"health"`
+		} `json:"resources"`
+	} `json:"status"`
+}
+
+func (s appStatus) done() bool {
+	op := s.Status.OperationState
+	return s.Operation == nil &&
+		(op == nil || op.Phase == "Succeeded") &&
+		s.Status.Sync.Status == "Synced" &&
+		s.Status.Health.Status == "Healthy"
+}
+
+// problems lists why the Application is not Synced/Healthy, one line per cause.
+func (s appStatus) problems() []string {
+	out := []string{fmt.Sprintf("sync=%s health=%s", s.Status.Sync.Status, s.Status.Health.Status)}
+	if s.Status.Health.Message != "" {
+		out = append(out, "health: "+s.Status.Health.Message)
+	}
+	for _, c := range s.Status.Conditions {
+		out = append(out, fmt.Sprintf("condition %s: %s", c.Type, c.Message))
+	}
+	if op := s.Status.OperationState; op != nil {
+		if op.Phase != "Succeeded" {
+			out = append(out, fmt.Sprintf("operation %s: %s", op.Phase, op.Message))
+		}
+		for _, r := range op.SyncResult.Resources {
+			if r.Status != "" && r.Status != "Synced" {
+				out = append(out, fmt.Sprintf("%s %s/%s: sync %s: %s", r.Kind, r.Namespace, r.Name, r.Status, r.Message))
+			}
+		}
+	}
+	for _, r := range s.Status.Resources {
+		switch {
+		case r.Status != "Synced":
+			out = append(out, fmt.Sprintf("%s %s/%s: %s", r.Kind, r.Namespace, r.Name, r.Status))
+		case r.Health != nil && r.Health.Status != "Healthy":
+			out = append(out, fmt.Sprintf("%s %s/%s: %s %s", r.Kind, r.Namespace, r.Name, r.Health.Status, r.Health.Message))
+		}
+	}
+	return out
+}
+
+// WaitSyncedHealthy polls the Application until the last sync finished and it
+// is Synced and Healthy. On timeout the error lists the failing resources and
+// their messages.
+func (a ArgoCD) WaitSyncedHealthy(ctx context.Context, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+	return poll(ctx, 2*time.Second, timeout, "application "+name+" Synced/Healthy", func(ctx context.Context) (bool, error) {
+		var s appStatus
+		if err := a.Kube.GetJSON(ctx, a.ns(), &s, "application", name); err != nil {
+			return false, err
+		}
+		if s.done() {
+			return true, nil
+		}
+		return false, errors.New(strings.Join(s.problems(), "\n  "))
+	})
+}
+
+// DeleteApplication deletes the Application and, through its finalizer, the
+// resources it created.
+func (a ArgoCD) DeleteApplication(ctx context.Context, name string) error {
+	patch := `{"metadata":{"finalizers":["resources-finalizer.argocd.argoproj.io"]}}`
+	if err := a.Kube.Patch(ctx, a.ns(), "application/"+name, "merge", patch); err != nil {
+		if IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return a.Kube.DeleteObject(ctx, a.ns(), "application/"+name)
+}
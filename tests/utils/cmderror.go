@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CmdError is the error of a command that failed: it exited non-zero, could
+// not start, or was killed when its context ended. Use errors.As to inspect it;
+// it unwraps to the cause (e.g. context.DeadlineExceeded, exec.ErrNotFound).
+type CmdError struct {
+	Name     string
+	Args     []string
+	ExitCode int // -1 when the command did not exit by itself
+	Stderr   string
+	Stdout   string
+	Duration time.Duration
+	TimedOut bool // killed because CmdOptions.Timeout (or the ctx deadline) expired
+	Err      error
+}
+
+func (e *CmdError) Error() string {
+	return fmt.Sprintf("cmd failed: %s %s (exit=%d, %s): %v\nstderr:\n%s\nstdout:\n%s",
+		e.Name, strings.Join(e.Args, " "), e.ExitCode, e.Duration.Round(time.Millisecond), e.Err, e.Stderr, e.Stdout)
+}
+
+func (e *CmdError) Unwrap() error {
+	return e.Err
+}
+
+// StderrContains reports whether err is (or wraps) a CmdError whose stderr contains s.
+func StderrContains(err error, s string) bool {
+	var ce *CmdError
+	return errors.As(err, &ce) && strings.Contains(ce.Stderr, s)
+}
+
+// IsNotFound reports whether a kubectl command failed because the object does not exist.
+func IsNotFound(err error) bool {
+	return StderrContains(err, "(NotFound)")
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Crossplane installs packages and waits on Crossplane objects through kubectl.
+type Crossplane struct {
+	Kube Kubectl
+}
+
+// XPackage is a Provider, Function or Configuration (pkg.crossplane.io/v1).
+type XPackage struct {
+	Kind       string // Provider | Function | Configuration
+	Name       string
+	Package    string // package image
+	PullPolicy string // packagePullPolicy; default: Never
+}
+
+// ApplyPackage creates (or updates) a package object. With PullPolicy Never the
+// package image must already be on the nodes (Docker.LoadIntoKind).
+func (x Crossplane) ApplyPackage(ctx context.Context, pkg XPackage) error {
+	if pkg.PullPolicy == "" {
+		pkg.PullPolicy = "Never"
+	}
+
+	manifest := fmt.Sprintf(`apiVersion: pkg.crossplane.io/v1
+kind: %s
+metadata:
+  name: %s
+spec:
+  package: %s
+  packagePullPolicy: %s
+`, pkg.Kind, pkg.Name, pkg.Package, pkg.PullPolicy)
+
+	if err := x.Kube.ApplyYAML(ctx, manifest); err != nil {
+		return fmt.Errorf("apply %s %s: %w", pkg.Kind, pkg.Name, err)
+	}
+	return nil
+}
+
+// Condition is a status condition as reported by Crossplane (and most controllers).
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type xObject struct {
+	Spec struct {
+		// resourceRefs moved under spec.crossplane in Crossplane v2
+		ResourceRefs []ResourceRef `json:"resourceRefs"`
+		Crossplane   struct {
+			ResourceRefs []ResourceRef `json:"resourceRefs"`
+		} `json:"crossplane"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+// ResourceRef points to a composed resource of a composite.
+type ResourceRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+}
+
+func (r ResourceRef) resource() string {
+	group := ""
+	if g, _, ok := strings.Cut(r.APIVersion, "/"); ok {
+		group = "." + g
+	}
+	return r.Kind + group + "/" + r.Name
+}
+
+func conditionTrue(conds []Condition, typ string) bool {
+	for _, c := range conds {
+		if c.Type == typ {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+func describeConditions(conds []Condition, types ...string) string {
+	var parts []string
+	for _, typ := range types {
+		found := false
+		for _, c := range conds {
+			if c.Type != typ {
+				continue
+			}
+			found = true
+			p := fmt.Sprintf("%s=%s", c.Type, c.Status)
+			if c.Reason != "" {
+				p += " (" + c.Reason + ")"
+			}
+			if c.Message != "" {
+				p += ": " + c.Message
+			}
+			parts = append(parts, p)
+		}
+		if !found {
+			parts = append(parts, typ+"=<unset>")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// waitConditions polls resource until every condition in want is True. On
+// timeout the error carries the conditions in want plus extra.
+func (x Crossplane) waitConditions(ctx context.Context, namespace, resource string, timeout time.Duration, want []string, extra ...string) (xObject, error) {
+	var last xObject
+	err := poll(ctx, 2*time.Second, timeout, resource+" "+strings.Join(want, ","), func(ctx context.Context) (bool, error) {
+		var obj xObject
+		if err := x.Kube.GetJSON(ctx, namespace, &obj, resource); err != nil {
+			return false, err
+		}
+		last = obj
+		for _, typ := range want {
+			if !conditionTrue(obj.Status.Conditions, typ) {
+				return false, errors.New(describeConditions(obj.Status.Conditions, append(want, extra...)...))
+			}
+		}
+		return true, nil
+	})
+	return last, err
+}
+
+// WaitPackageHealthy waits until a package reports Installed=True and Healthy=True.
+func (x Crossplane) WaitPackageHealthy(ctx context.Context, kind, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+	resource := strings.ToLower(kind) + ".pkg.crossplane.io/" + name
+	_, err := x.waitConditions(ctx, "", resource, timeout, []string{"Installed", "Healthy"})
+	return err
+}
+
+// ApplyAndWaitReady applies the Composite/Claim manifest at path and waits for
+// resource (declared in it) to be Ready.
+func (x Crossplane) ApplyAndWaitReady(ctx context.Context, path, namespace, resource string, timeout time.Duration) error {
+	if err := x.Kube.Apply(ctx, ApplyOpts{Path: path, Namespace: namespace}); err != nil {
+		return err
+	}
+	return x.WaitReady(ctx, namespace, resource, timeout)
+}
+
+// WaitReady waits until a Composite or Claim (e.g. "xbucket/my-bucket") reports
+// Ready=True. On timeout the error includes its Synced/Ready messages and those
+// of every composed resource that is not ready yet, which is usually where the
+// real cause is.
+func (x Crossplane) WaitReady(ctx context.Context, namespace, resource string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+
+	last, err := x.waitConditions(ctx, namespace, resource, timeout, []string{"Ready"}, "Synced")
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	refs := append(last.Spec.ResourceRefs, last.Spec.Crossplane.ResourceRefs...)
+	var lines []string
+	for _, r := range refs {
+		var obj xObject
+		ns := r.Namespace
+		if ns == "" {
+			ns = namespace
+		}
+		if gerr := x.Kube.GetJSON(ctx, ns, &obj, r.resource()); gerr != nil {
+			lines = append(lines, fmt.Sprintf("%s: %v", r.resource(), gerr))
+			continue
+		}
+		if !conditionTrue(obj.Status.Conditions, "Ready") || !conditionTrue(obj.Status.Conditions, "Synced") {
+			lines = append(lines, fmt.Sprintf("%s: %s", r.resource(), describeConditions(obj.Status.Conditions, "Synced", "Ready")))
+		}
+	}
+	if len(lines) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w\ncomposed resources not ready:\n  %s", err, strings.Join(lines, "\n  "))
+}
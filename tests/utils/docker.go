@@ -10,6 +10,8 @@ import (
 
 type Docker struct {
 	Timeout time.Duration
+	Runner  Runner       // default: ExecRunner
+	Retry   *RetryPolicy // default: DefaultRetry; NoRetry disables
 }
 
 func (d Docker) Pull(ctx context.Context, image string) error {
@@ -21,7 +23,7 @@ func (d Docker) Pull(ctx context.Context, image string) error {
 		timeout = 5 * time.Minute
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(d.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(d.Retry)},
 		"docker", "pull", image,
 	)
 	return err
@@ -36,13 +38,16 @@ func (d Docker) ImageExistsLocal(ctx context.Context, image string) (bool, error
 		timeout = 20 * time.Second
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(d.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(d.Retry)},
 		"docker", "image", "inspect", image,
 	)
-	if err != nil {
-		// inspect retorna erro se não existe localmente
+	// só "No such image" significa ausente; daemon fora do ar etc. são erros de verdade
+	if StderrContains(err, "No such image") {
 		return false, nil
 	}
+	if err != nil {
+		return false, fmt.Errorf("docker image inspect %s: %w", image, err)
+	}
 	return true, nil
 }
 
@@ -72,7 +77,7 @@ func (d Docker) LoadIntoKind(ctx context.Context, kindClusterName, image string)
 		timeout = 2 * time.Minute
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(d.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(d.Retry)},
 		"kind", "load", "docker-image", image,
 		"--name", kindClusterName,
 	)
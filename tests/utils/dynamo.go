@@ -55,26 +55,21 @@ func (c *DynamoClient) WaitTableStatus(ctx context.Context, table string, want t
 	if timeout <= 0 {
 		timeout = 30 * time.Second
 	}
-	deadline := time.Now().Add(timeout)
-	backoff := 300 * time.Millisecond
-
-	for {
-		if time.Now().After(deadline) {
-			return fmt.Errorf("timeout waiting table %q status=%s", table, want)
-		}
-
+	what := fmt.Sprintf("table %q status=%s", table, want)
+	return poll(ctx, 500*time.Millisecond, timeout, what, func(ctx context.Context) (bool, error) {
 		out, err := c.Client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 			TableName: aws.String(table),
 		})
-		if err == nil && out.Table != nil && out.Table.TableStatus == want {
-			return nil
+		switch {
+		case err != nil:
+			return false, err
+		case out.Table == nil:
+			return false, errors.New("not described yet")
+		case out.Table.TableStatus == want:
+			return true, nil
 		}
-
-		time.Sleep(backoff)
-		if backoff < 2*time.Second {
-			backoff *= 2
-		}
-	}
+		return false, fmt.Errorf("status=%s", out.Table.TableStatus)
+	})
 }
 
 // ListTables returns all tables (paginado).
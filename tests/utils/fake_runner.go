@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Call is one command seen by a FakeRunner.
+type Call struct {
+	Name  string
+	Args  []string
+	Stdin string
+	Env   map[string]string
+	Dir   string
+}
+
+func (c Call) argv() []string {
+	return append([]string{c.Name}, c.Args...)
+}
+
+func (c Call) String() string {
+	return strings.Join(c.argv(), " ")
+}
+
+// FakeRunner is a scripted Runner for unit tests. Each Expect describes one
+// call by its exact argv (and optionally stdin/env) and what it returns;
+// calls may arrive in any order, so helpers that run commands concurrently
+// can be tested too. Every call is recorded in Calls.
+//
+//	r := &FakeRunner{}
+//	r.Expect("kind", "get", "clusters").Returns("a\nb\n", "", 0)
+//	_, err := Kind{Runner: r}.Clusters(ctx)
+//	if err := r.Err(); err != nil { t.Fatal(err) }
+type FakeRunner struct {
+	mu       sync.Mutex
+	expected []*Expectation
+	problems []string
+	Calls    []Call
+}
+
+// Expectation is one scripted call of a FakeRunner.
+type Expectation struct {
+	argv     []string
+	stdin    *string
+	env      map[string]string
+	res      CmdResult
+	err      error
+	consumed bool
+}
+
+// Expect scripts a call with exactly argv (command name first). By default it
+// succeeds with empty output.
+func (f *FakeRunner) Expect(argv ...string) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e := &Expectation{argv: argv}
+	f.expected = append(f.expected, e)
+	return e
+}
+
+// WithStdin requires the call to receive exactly stdin.
+func (e *Expectation) WithStdin(stdin string) *Expectation {
+	e.stdin = &stdin
+	return e
+}
+
+// WithEnv requires the call to set exactly these env overrides.
+func (e *Expectation) WithEnv(env map[string]string) *Expectation {
+	e.env = env
+	return e
+}
+
+// Returns sets the output of the call; a non-zero exit makes it fail with a
+// *CmdError, like ExecWithResult.
+func (e *Expectation) Returns(stdout, stderr string, exit int) *Expectation {
+	e.res = CmdResult{Stdout: stdout, Stderr: stderr, ExitCode: exit}
+	return e
+}
+
+// Fails makes the call return a *CmdError wrapping err (e.g.
+// context.DeadlineExceeded for a timeout) with exit code -1.
+func (e *Expectation) Fails(err error) *Expectation {
+	e.res.ExitCode = -1
+	e.err = err
+	return e
+}
+
+// Run honours opt.Retry like ExecRunner, so a retried call consumes one
+// expectation per attempt.
+func (f *FakeRunner) Run(ctx context.Context, opt CmdOptions, name string, args ...string) (CmdResult, error) {
+	return withRetry(ctx, opt, name, args, func(context.Context) (CmdResult, error) {
+		return f.runOnce(opt, name, args)
+	})
+}
+
+func (f *FakeRunner) runOnce(opt CmdOptions, name string, args []string) (CmdResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := Call{Name: name, Args: slices.Clone(args), Stdin: opt.Stdin, Env: maps.Clone(opt.Env), Dir: opt.Dir}
+	f.Calls = append(f.Calls, call)
+
+	var e *Expectation
+	for _, cand := range f.expected {
+		if !cand.consumed && slices.Equal(cand.argv, call.argv()) {
+			e = cand
+			break
+		}
+	}
+	if e == nil {
+		f.problems = append(f.problems, "unexpected call: "+call.String())
+		return CmdResult{ExitCode: -1}, fmt.Errorf("fake runner: unexpected call: %s", call)
+	}
+	e.consumed = true
+
+	if e.stdin != nil && *e.stdin != call.Stdin {
+		f.problems = append(f.problems, fmt.Sprintf("%s: stdin = %q, want %q", call, call.Stdin, *e.stdin))
+	}
+	if e.env != nil && !maps.Equal(e.env, call.Env) {
+		f.problems = append(f.problems, fmt.Sprintf("%s: env = %v, want %v", call, call.Env, e.env))
+	}
+
+	if e.err == nil && e.res.ExitCode == 0 {
+		return e.res, nil
+	}
+	ce := &CmdError{
+		Name:     name,
+		Args:     call.Args,
+		ExitCode: e.res.ExitCode,
+		Stderr:   e.res.Stderr,
+		Stdout:   e.res.Stdout,
+		TimedOut: errors.Is(e.err, context.DeadlineExceeded),
+		Err:      e.err,
+	}
+	if ce.Err == nil {
+		ce.Err = fmt.Errorf("exit status %d", e.res.ExitCode)
+	}
+	return e.res, ce
+}
+
+// Err reports unexpected calls, stdin/env mismatches and expectations that
+// were never called, or nil when the script ran exactly.
+func (f *FakeRunner) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for _, p := range f.problems {
+		errs = append(errs, errors.New(p))
+	}
+	for _, e := range f.expected {
+		if !e.consumed {
+			errs = append(errs, fmt.Errorf("expected call not made: %s", strings.Join(e.argv, " ")))
+		}
+	}
+	return errors.Join(errs...)
+}
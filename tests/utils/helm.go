@@ -10,11 +10,14 @@ import (
 type Helm struct {
 	KubeContext string
 	Timeout     time.Duration
+	Runner      Runner       // default: ExecRunner
+	Retry       *RetryPolicy // default: DefaultRetry; NoRetry disables
 }
 
 type HelmInstallOpts struct {
 	Release   string
 	Chart     string
+	Version   string
 	Namespace string
 	Values    []string
 	Set       []string
@@ -42,6 +45,9 @@ func (h Helm) UpgradeInstall(ctx context.Context, opt HelmInstallOpts) error {
 		"--namespace", opt.Namespace,
 	}
 
+	if opt.Version != "" {
+		args = append(args, "--version", opt.Version)
+	}
 	if opt.CreateNS {
 		args = append(args, "--create-namespace")
 	}
@@ -57,16 +63,10 @@ func (h Helm) UpgradeInstall(ctx context.Context, opt HelmInstallOpts) error {
 	}
 	args = append(args, opt.ExtraArgs...)
 
-	res, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	// stderr/stdout are part of the returned error
+	_, err := orExec(h.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(h.Retry)},
 		"helm", args...,
 	)
-
-	if err != nil {
-		fmt.Printf("HELM STDOUT:\n%s\n", res.Stdout)
-		fmt.Printf("HELM STDERR:\n%s\n", res.Stderr)
-		return err
-	}
-
 	return err
 }
 
@@ -76,7 +76,7 @@ func (h Helm) DependencyBuild(ctx context.Context, chartPath string) error {
 		timeout = 2 * time.Minute
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(h.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(h.Retry)},
 		"helm",
 		"dependency", "build", chartPath,
 	)
@@ -96,7 +96,7 @@ func (h Helm) Uninstall(ctx context.Context, release, namespace string) error {
 		timeout = 2 * time.Minute
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(h.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(h.Retry)},
 		"helm",
 		"--kube-context", h.KubeContext,
 		"uninstall", release,
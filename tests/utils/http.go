@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WaitHTTPStatus polls GET url until it answers with want. Connection errors
+// are retried (the port mapping answers before the pod does).
+func WaitHTTPStatus(ctx context.Context, url string, want int, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	return poll(ctx, time.Second, timeout, fmt.Sprintf("GET %s status=%d", url, want), func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return true, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode == want {
+			return true, nil
+		}
+		return false, fmt.Errorf("status=%d body=%q", resp.StatusCode, strings.TrimSpace(string(body)))
+	})
+}
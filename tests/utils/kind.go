@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Kind struct {
+	Timeout time.Duration
+	Runner  Runner       // default: ExecRunner
+	Retry   *RetryPolicy // default: DefaultRetry; NoRetry disables
+}
+
+func (k Kind) CreateCluster(ctx context.Context, name, configPath string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("kind cluster name is empty")
+	}
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	// not retried: a failed create leaves a half-created cluster behind
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout},
+		"kind", "create", "cluster",
+		"--name", name,
+		"--config", configPath,
+	)
+	return err
+}
+
+// DeleteCluster deletes a kind cluster; deleting a missing cluster is not an error.
+func (k Kind) DeleteCluster(ctx context.Context, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("kind cluster name is empty")
+	}
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kind", "delete", "cluster", "--name", name,
+	)
+	return err
+}
+
+// Clusters lists the existing kind clusters (kind get clusters).
+func (k Kind) Clusters(ctx context.Context) ([]string, error) {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kind", "get", "clusters",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// "No kind clusters found." goes to stderr, stdout is then empty
+	var out []string
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
+
+func (k Kind) ClusterExists(ctx context.Context, name string) (bool, error) {
+	clusters, err := k.Clusters(ctx)
+	if err != nil {
+		return false, fmt.Errorf("kind get clusters: %w", err)
+	}
+	return slices.Contains(clusters, name), nil
+}
@@ -2,13 +2,19 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Kubectl struct {
 	Context string
 	Timeout time.Duration
+	Runner  Runner       // default: ExecRunner
+	Retry   *RetryPolicy // default: DefaultRetry; NoRetry disables
 }
 
 func (k Kubectl) EnsureNamespace(ctx context.Context, name string) error {
@@ -23,7 +29,7 @@ func (k Kubectl) EnsureNamespace(ctx context.Context, name string) error {
 		name,
 	)
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout, Stdin: manifest},
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry), Stdin: manifest},
 		"kubectl",
 		"--context", k.Context,
 		"apply", "-f", "-",
@@ -32,30 +38,312 @@ func (k Kubectl) EnsureNamespace(ctx context.Context, name string) error {
 }
 
 func (k Kubectl) ApplyFile(ctx context.Context, path string) error {
+	return k.Apply(ctx, ApplyOpts{Path: path})
+}
+
+type ApplyOpts struct {
+	Path       string // file, directory or "-" (Stdin)
+	Stdin      string
+	Namespace  string // for objects that do not set metadata.namespace
+	ServerSide bool   // --server-side --force-conflicts
+}
+
+func (k Kubectl) Apply(ctx context.Context, opts ApplyOpts) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Minute
+	}
+
+	args := []string{"--context", k.Context}
+	if opts.Namespace != "" {
+		args = append(args, "-n", opts.Namespace)
+	}
+	args = append(args, "apply", "-f", opts.Path)
+	if opts.ServerSide {
+		args = append(args, "--server-side", "--force-conflicts")
+	}
+
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry), Stdin: opts.Stdin}, "kubectl", args...)
+	return err
+}
+
+// ApplyYAML applies an inline manifest.
+func (k Kubectl) ApplyYAML(ctx context.Context, manifest string) error {
+	return k.Apply(ctx, ApplyOpts{Path: "-", Stdin: manifest})
+}
+
+// DeleteFile deletes what path declares; missing objects are not an error.
+func (k Kubectl) DeleteFile(ctx context.Context, path string) error {
+	return k.Delete(ctx, "", path)
+}
+
+// Delete deletes what path declares, in namespace when the objects do not set one.
+func (k Kubectl) Delete(ctx context.Context, namespace, path string) error {
 	timeout := k.Timeout
 	if timeout == 0 {
 		timeout = 1 * time.Minute
 	}
 
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	args := []string{"--context", k.Context}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "delete", "-f", path, "--ignore-not-found", "--wait")
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)}, "kubectl", args...)
+	return err
+}
+
+// DeleteObject deletes one object (e.g. application/x) and waits for it to go;
+// a missing object is not an error.
+func (k Kubectl) DeleteObject(ctx context.Context, namespace, resource string) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
 		"kubectl",
 		"--context", k.Context,
-		"apply", "-f", path,
+		"-n", namespace,
+		"delete", resource,
+		"--ignore-not-found", "--wait",
 	)
 	return err
 }
 
 func (k Kubectl) WaitDeploymentReady(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	return k.WaitRolloutStatus(ctx, namespace, "deployment/"+name, timeout)
+}
+
+// WaitRolloutStatus waits for a deployment/, statefulset/ or daemonset/ resource to finish rolling out.
+func (k Kubectl) WaitRolloutStatus(ctx context.Context, namespace, resource string, timeout time.Duration) error {
 	if timeout <= 0 {
 		timeout = 2 * time.Minute
 	}
-	_, err := ExecWithResult(ctx, CmdOptions{Timeout: timeout},
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
 		"kubectl",
 		"--context", k.Context,
 		"-n", namespace,
 		"rollout", "status",
-		"deployment/"+name,
+		resource,
 		"--timeout", timeout.String(),
 	)
 	return err
 }
+
+// WaitJobComplete waits until job/name reports condition Complete. A Job that
+// reports Failed (e.g. BackoffLimitExceeded) fails right away with its reason
+// instead of running into the timeout.
+func (k Kubectl) WaitJobComplete(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return poll(ctx, 2*time.Second, timeout, "job/"+name+" Complete", func(ctx context.Context) (bool, error) {
+		var job struct {
+			Status struct {
+				Conditions []Condition `json:"conditions"`
+			} `json:"status"`
+		}
+		if err := k.GetJSON(ctx, namespace, &job, "job/"+name); err != nil {
+			return false, err
+		}
+		conds := job.Status.Conditions
+		switch {
+		case conditionTrue(conds, "Failed"):
+			return true, fmt.Errorf("job/%s failed: %s", name, describeConditions(conds, "Failed"))
+		case conditionTrue(conds, "Complete"):
+			return true, nil
+		default:
+			return false, errors.New(describeConditions(conds, "Complete"))
+		}
+	})
+}
+
+// WaitCRDEstablished waits until crd/name reports condition=established, i.e. its
+// custom resources can be created.
+func (k Kubectl) WaitCRDEstablished(ctx context.Context, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kubectl",
+		"--context", k.Context,
+		"wait", "--for=condition=established",
+		"crd/"+name,
+		"--timeout", timeout.String(),
+	)
+	return err
+}
+
+// WaitFor waits until resource (e.g. pod/x) reports condition (e.g. Ready).
+func (k Kubectl) WaitFor(ctx context.Context, namespace, resource, condition string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kubectl",
+		"--context", k.Context,
+		"-n", namespace,
+		"wait", "--for=condition="+condition,
+		resource,
+		"--timeout", timeout.String(),
+	)
+	return err
+}
+
+// Patch runs kubectl patch with patchType merge, json or strategic.
+func (k Kubectl) Patch(ctx context.Context, namespace, resource, patchType, patch string) error {
+	return k.PatchSubresource(ctx, namespace, resource, "", patchType, patch)
+}
+
+// PatchSubresource patches a subresource such as status (empty: the object itself).
+func (k Kubectl) PatchSubresource(ctx context.Context, namespace, resource, subresource, patchType, patch string) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	args := []string{
+		"--context", k.Context,
+		"-n", namespace,
+		"patch", resource,
+		"--type", patchType,
+		"-p", patch,
+	}
+	if subresource != "" {
+		args = append(args, "--subresource", subresource)
+	}
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)}, "kubectl", args...)
+	return err
+}
+
+// Exec runs a command in the first container of pod and returns its stdout.
+func (k Kubectl) Exec(ctx context.Context, namespace, pod string, command ...string) (string, error) {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Minute
+	}
+
+	args := []string{"--context", k.Context, "-n", namespace, "exec", pod, "--"}
+	// not retried: the command may have run before the connection dropped
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout}, "kubectl", append(args, command...)...)
+	return res.Stdout, err
+}
+
+// CopyTo copies a local file or directory into pod (kubectl cp, needs tar in the image).
+func (k Kubectl) CopyTo(ctx context.Context, src, namespace, pod, dst string) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Minute
+	}
+
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kubectl",
+		"--context", k.Context,
+		"cp", src, namespace+"/"+pod+":"+dst,
+	)
+	return err
+}
+
+// Create runs kubectl create -f path (needed for generateName) and returns
+// the created objects as kind.group/name.
+func (k Kubectl) Create(ctx context.Context, namespace, path string) ([]string, error) {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 1 * time.Minute
+	}
+
+	// not retried: with generateName every attempt would create another object
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout},
+		"kubectl",
+		"--context", k.Context,
+		"-n", namespace,
+		"create", "-f", path,
+		"-o", "name",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(res.Stdout), nil
+}
+
+// Logs returns the last tail lines of a pod container (all lines when tail <= 0).
+func (k Kubectl) Logs(ctx context.Context, namespace, pod, container string, tail int) (string, error) {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	args := []string{"--context", k.Context, "-n", namespace, "logs", pod}
+	if container != "" {
+		args = append(args, "-c", container)
+	}
+	if tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)}, "kubectl", args...)
+	return res.Stdout, err
+}
+
+// RunOnce runs a throwaway pod (kubectl run --rm --attach) and returns its output.
+func (k Kubectl) RunOnce(ctx context.Context, namespace, name, image string, args ...string) (string, error) {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	cmd := []string{
+		"--context", k.Context,
+		"-n", namespace,
+		"run", name,
+		"--image", image,
+		"--restart=Never", "--rm", "--attach", "--quiet",
+		"--",
+	}
+	// not retried: the pod may have run before the connection dropped
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout}, "kubectl", append(cmd, args...)...)
+	return res.Stdout, err
+}
+
+// GetJSON runs kubectl get <args> -o json and decodes the result into out.
+// namespace may be empty for cluster-scoped resources.
+func (k Kubectl) GetJSON(ctx context.Context, namespace string, out any, args ...string) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	cmd := []string{"--context", k.Context}
+	if namespace != "" {
+		cmd = append(cmd, "-n", namespace)
+	}
+	cmd = append(cmd, "get")
+	cmd = append(cmd, args...)
+	cmd = append(cmd, "-o", "json")
+
+	res, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)}, "kubectl", cmd...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(res.Stdout), out); err != nil {
+		return fmt.Errorf("decode kubectl get %v: %w", args, err)
+	}
+	return nil
+}
+
+// LabelNodes sets (or overwrites) a label on every node of the cluster.
+func (k Kubectl) LabelNodes(ctx context.Context, key, value string) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	_, err := orExec(k.Runner).Run(ctx, CmdOptions{Timeout: timeout, Retry: retryOr(k.Retry)},
+		"kubectl",
+		"--context", k.Context,
+		"label", "nodes", "--all",
+		key+"="+value,
+		"--overwrite",
+	)
+	return err
+}
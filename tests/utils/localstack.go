@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LocalStackHealth is the body of GET /_localstack/health.
+type LocalStackHealth struct {
+	Edition  string            `json:"edition"`
+	Version  string            `json:"version"`
+	Services map[string]string `json:"services"` // service -> available | running | disabled | error ...
+}
+
+// localstackReady are the states of a service that can take requests
+// ("available" services start lazily on first use).
+func localstackReady(state string) bool {
+	return state == "available" || state == "running"
+}
+
+// WaitLocalStackHealthy polls the LocalStack health endpoint (e.g.
+// http://localhost:4566/_localstack/health) until every service in services is
+// available or running. The timeout error lists the services that are not.
+func WaitLocalStackHealthy(ctx context.Context, url string, services []string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	what := fmt.Sprintf("LocalStack services %v at %s", services, url)
+	return poll(ctx, time.Second, timeout, what, func(ctx context.Context) (bool, error) {
+		h, err := getLocalStackHealth(ctx, client, url)
+		if err != nil {
+			return false, err
+		}
+		var pending []string
+		for _, svc := range services {
+			if state := h.Services[svc]; !localstackReady(state) {
+				if state == "" {
+					state = "unknown"
+				}
+				pending = append(pending, svc+"="+state)
+			}
+		}
+		if len(pending) == 0 {
+			return true, nil
+		}
+		sort.Strings(pending)
+		return false, fmt.Errorf("not ready: %s", strings.Join(pending, ", "))
+	})
+}
+
+func getLocalStackHealth(ctx context.Context, client *http.Client, url string) (LocalStackHealth, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return LocalStackHealth{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return LocalStackHealth{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LocalStackHealth{}, fmt.Errorf("status=%d", resp.StatusCode)
+	}
+	var h LocalStackHealth
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return LocalStackHealth{}, fmt.Errorf("decode health: %w", err)
+	}
+	return h, nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NatsClient wraps a NATS connection and its JetStream context.
+// From the host, NATS on cluster-b is reached through the NodePort mapping
+// in infra/kind/cluster-b.yaml (nats://localhost:4222).
+type NatsClient struct {
+	Conn *nats.Conn
+	JS   jetstream.JetStream
+}
+
+func NewNats(url string) (*NatsClient, error) {
+	if url == "" {
+		url = "nats://localhost:4222"
+	}
+
+	nc, err := nats.Connect(url,
+		nats.Name("e2e-tests"),
+		nats.Timeout(10*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", url, err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &NatsClient{Conn: nc, JS: js}, nil
+}
+
+func (c *NatsClient) Close() {
+	c.Conn.Close()
+}
+
+// CreateStream creates (or updates) a memory stream capturing subjects.
+func (c *NatsClient) CreateStream(ctx context.Context, name string, subjects ...string) error {
+	if len(subjects) == 0 {
+		return errors.New("stream needs at least one subject")
+	}
+	_, err := c.JS.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: subjects,
+		Storage:  jetstream.MemoryStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("create stream %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteStream deletes a stream; a missing stream is not an error.
+func (c *NatsClient) DeleteStream(ctx context.Context, name string) error {
+	err := c.JS.DeleteStream(ctx, name)
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("delete stream %s: %w", name, err)
+	}
+	return nil
+}
+
+// CreateConsumer creates (or updates) a durable pull consumer on stream.
+// filterSubject may be empty to consume every subject of the stream.
+func (c *NatsClient) CreateConsumer(ctx context.Context, stream, durable, filterSubject string) error {
+	_, err := c.JS.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: filterSubject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("create consumer %s/%s: %w", stream, durable, err)
+	}
+	return nil
+}
+
+// Publish publishes to a subject captured by a stream and waits for the ack.
+func (c *NatsClient) Publish(ctx context.Context, subject string, data []byte) error {
+	if _, err := c.JS.Publish(ctx, subject, data); err != nil {
+		return fmt.Errorf("publish %s: %w", subject, err)
+	}
+	return nil
+}
+
+// NatsMessage is a message read back from a stream.
+type NatsMessage struct {
+	Subject string
+	Data    []byte
+	Header  nats.Header
+}
+
+// WaitMessage reads stream from the beginning (filtered by subject, which may
+// be empty or use wildcards) until match returns true. It uses an ephemeral
+// ordered consumer, so it does not disturb durable consumers.
+//
+// Example:
+//
+//	msg, err := nc.WaitMessage(ctx, "ORDERS", "orders.created", 30*time.Second, func(m NatsMessage) bool {
+//		return bytes.Contains(m.Data, []byte(`"id":"123"`))
+//	})
+func (c *NatsClient) WaitMessage(ctx context.Context, stream, subject string, timeout time.Duration, match func(NatsMessage) bool) (NatsMessage, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	cfg := jetstream.OrderedConsumerConfig{DeliverPolicy: jetstream.DeliverAllPolicy}
+	if subject != "" {
+		cfg.FilterSubjects = []string{subject}
+	}
+	cons, err := c.JS.OrderedConsumer(ctx, stream, cfg)
+	if err != nil {
+		return NatsMessage{}, fmt.Errorf("consume %s: %w", stream, err)
+	}
+
+	var found NatsMessage
+	seen := 0
+	what := fmt.Sprintf("message on %s (subject=%q)", stream, subject)
+	// Fetch blocks up to a second, so no extra interval between polls
+	err = poll(ctx, 0, timeout, what, func(ctx context.Context) (bool, error) {
+		batch, err := cons.Fetch(10, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return true, fmt.Errorf("fetch %s: %w", stream, err)
+		}
+		for m := range batch.Messages() {
+			seen++
+			msg := NatsMessage{Subject: m.Subject(), Data: m.Data(), Header: m.Headers()}
+			if match == nil || match(msg) {
+				found = msg
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("%d seen, none matched", seen)
+	})
+	return found, err
+}
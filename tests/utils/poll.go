@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// poll calls check every interval until it reports done, ctx ends or timeout
+// expires. check returns done=true to stop, with err as the result (so a
+// permanent failure ends the wait at once), or done=false with an err that
+// describes what it observed; the timeout error wraps the last one.
+// With interval 0, check is expected to block (a fetch with a max wait).
+func poll(ctx context.Context, interval, timeout time.Duration, what string, check func(ctx context.Context) (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := check(ctx)
+		if done {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("timeout after %s waiting %s: %w", timeout, what, err)
+			}
+			return fmt.Errorf("timeout after %s waiting %s", timeout, what)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
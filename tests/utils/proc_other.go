@@ -0,0 +1,22 @@
+//go:build !unix
+
+package utils
+
+import "os/exec"
+
+// Without process groups only the direct child can be stopped, and only hard.
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func groupAlive(cmd *exec.Cmd) bool { return false }
+
+func terminateGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func killGroup(cmd *exec.Cmd) error {
+	return terminateGroup(cmd)
+}
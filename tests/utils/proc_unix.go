@@ -0,0 +1,42 @@
+//go:build unix
+
+package utils
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd as the leader of a new process group, so a
+// cancellation reaches the processes it spawned (helm hooks, kubectl plugins,
+// sh -c pipelines) and not only the direct child.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup sends sig to every process in the group of cmd. A group that is
+// already gone is not an error.
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// groupAlive reports whether any process of the group of cmd still exists.
+func groupAlive(cmd *exec.Cmd) bool {
+	return cmd.Process != nil && syscall.Kill(-cmd.Process.Pid, 0) == nil
+}
+
+func terminateGroup(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGTERM)
+}
+
+func killGroup(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGKILL)
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient wraps a go-redis client. From the host, Redis on cluster-b is
+// reached through the NodePort mapping in infra/kind/cluster-b.yaml (localhost:6379).
+type RedisClient struct {
+	Client *redis.Client
+}
+
+func NewRedis(ctx context.Context, addr string) (*RedisClient, error) {
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	c := redis.NewClient(&redis.Options{
+		Addr:        addr,
+		DialTimeout: 5 * time.Second,
+	})
+	if err := c.Ping(ctx).Err(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("ping redis %s: %w", addr, err)
+	}
+	return &RedisClient{Client: c}, nil
+}
+
+func (c *RedisClient) Close() error {
+	return c.Client.Close()
+}
+
+// WaitKey polls until key exists and returns its (string) value.
+func (c *RedisClient) WaitKey(ctx context.Context, key string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	var val string
+	err := poll(ctx, 500*time.Millisecond, timeout, fmt.Sprintf("key %q", key), func(ctx context.Context) (bool, error) {
+		v, err := c.Client.Get(ctx, key).Result()
+		switch {
+		case err == nil:
+			val = v
+			return true, nil
+		case errors.Is(err, redis.Nil):
+			return false, errors.New("missing")
+		}
+		return true, fmt.Errorf("get %s: %w", key, err)
+	})
+	return val, err
+}
+
+// AssertKeyEquals waits until key holds expected; the error reports the last value seen.
+func (c *RedisClient) AssertKeyEquals(ctx context.Context, key, expected string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return poll(ctx, 500*time.Millisecond, timeout, fmt.Sprintf("%s=%q", key, expected), func(ctx context.Context) (bool, error) {
+		val, err := c.Client.Get(ctx, key).Result()
+		switch {
+		case err == nil && val == expected:
+			return true, nil
+		case err == nil:
+			return false, fmt.Errorf("last: %q", val)
+		case errors.Is(err, redis.Nil):
+			return false, errors.New("last: <missing>")
+		}
+		return true, fmt.Errorf("get %s: %w", key, err)
+	})
+}
+
+// WaitStreamEntry reads stream from the beginning until match returns true for
+// an entry, blocking for new entries until timeout.
+//
+// Example:
+//
+//	e, err := rc.WaitStreamEntry(ctx, "events", 30*time.Second, func(e redis.XMessage) bool {
+//		return e.Values["type"] == "user.created"
+//	})
+func (c *RedisClient) WaitStreamEntry(ctx context.Context, stream string, timeout time.Duration, match func(redis.XMessage) bool) (redis.XMessage, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	var found redis.XMessage
+	lastID, seen := "0", 0
+	// XREAD blocks up to a second, so no extra interval between polls
+	err := poll(ctx, 0, timeout, fmt.Sprintf("entry on stream %q", stream), func(ctx context.Context) (bool, error) {
+		res, err := c.Client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{stream, lastID},
+			Count:   100,
+			Block:   time.Second,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return true, fmt.Errorf("xread %s: %w", stream, err)
+		}
+
+		for _, s := range res {
+			for _, m := range s.Messages {
+				seen++
+				lastID = m.ID
+				if match == nil || match(m) {
+					found = m
+					return true, nil
+				}
+			}
+		}
+		return false, fmt.Errorf("%d seen, none matched", seen)
+	})
+	return found, err
+}
+
+// FlushNamespacePrefix deletes every key starting with prefix (SCAN, never
+// FLUSHALL), so tests sharing the instance stay isolated. Returns how many were deleted.
+func (c *RedisClient) FlushNamespacePrefix(ctx context.Context, prefix string) (int, error) {
+	if prefix == "" {
+		return 0, errors.New("prefix is empty (refusing to delete every key)")
+	}
+
+	deleted := 0
+	iter := c.Client.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := c.Client.Del(ctx, batch...).Result()
+		deleted += int(n)
+		batch = batch[:0]
+		return err
+	}
+
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := flush(); err != nil {
+				return deleted, fmt.Errorf("del %s*: %w", prefix, err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, fmt.Errorf("scan %s*: %w", prefix, err)
+	}
+	if err := flush(); err != nil {
+		return deleted, fmt.Errorf("del %s*: %w", prefix, err)
+	}
+	return deleted, nil
+}
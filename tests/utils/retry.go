@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+// RetryPolicy retries a command that failed with a transient error (see
+// IsTransient). Zero fields take the defaults below.
+type RetryPolicy struct {
+	MaxAttempts    int           // including the first one; default: 3
+	Backoff        time.Duration // delay before the second attempt, doubled after each one; default: 1s
+	MaxBackoff     time.Duration // default: 15s
+	Jitter         float64       // up to this fraction of the delay is added at random; default: 0.2
+	AttemptTimeout time.Duration // limit of each attempt, if below CmdOptions.Timeout; a timed out attempt is retried
+	MaxElapsed     time.Duration // cap on all attempts plus backoff; default: none
+
+	// Retriable decides whether a failed attempt is worth another; default: IsTransient.
+	Retriable func(name string, res CmdResult) bool
+}
+
+// DefaultRetry is what Helm, Kubectl, Kind and Docker use when their Retry field is nil.
+var DefaultRetry = RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 15 * time.Second}
+
+// NoRetry runs commands exactly once.
+var NoRetry = &RetryPolicy{MaxAttempts: 1}
+
+func retryOr(p *RetryPolicy) *RetryPolicy {
+	if p == nil {
+		return &DefaultRetry
+	}
+	return p
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 15 * time.Second
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	if p.Retriable == nil {
+		p.Retriable = IsTransient
+	}
+	return p
+}
+
+// delay is the wait after the given (1-based) failed attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff << (attempt - 1)
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// transientCommon are failures of any tool talking to a cluster or registry
+// that is not (yet) reachable.
+var transientCommon = []string{
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"http2: client connection lost",
+	"unexpected EOF",
+}
+
+// transientByTool are stderr fragments each tool prints for errors that go
+// away by themselves: API server starting, etcd leader election, webhooks and
+// CRDs registered by a component installed a moment before.
+var transientByTool = map[string][]string{
+	"kubectl": {
+		"Unable to connect to the server",
+		"the server is currently unable to handle the request",
+		"etcdserver: leader changed",
+		"etcdserver: request timed out",
+		"ensure CRDs are installed first",
+		"failed calling webhook",
+		"the object has been modified",
+	},
+	"helm": {
+		"Kubernetes cluster unreachable",
+		"the server is currently unable to handle the request",
+		"etcdserver: leader changed",
+		"etcdserver: request timed out",
+		"failed calling webhook",
+	},
+	"kind": {
+		"failed to load image",
+		"Cannot connect to the Docker daemon",
+	},
+	"docker": {
+		"toomanyrequests",
+		"net/http: request canceled",
+		"Client.Timeout exceeded",
+		"Cannot connect to the Docker daemon",
+	},
+}
+
+// IsTransient reports whether the stderr of a failed name command (kubectl,
+// helm, kind, docker) shows an error worth retrying.
+func IsTransient(name string, res CmdResult) bool {
+	for _, pats := range [][]string{transientCommon, transientByTool[name]} {
+		for _, pat := range pats {
+			if strings.Contains(res.Stderr, pat) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withRetry runs once according to opt.Retry. Every attempt gets its own
+// opt.Timeout (or the shorter AttemptTimeout), so a retry after a late transient
+// failure is not left without time; MaxElapsed caps the whole. Each failed
+// attempt is logged to opt.Output (or stderr) and, when all fail, the error
+// lists every attempt.
+func withRetry(ctx context.Context, opt CmdOptions, name string, args []string, once func(context.Context) (CmdResult, error)) (CmdResult, error) {
+	if opt.Retry == nil || opt.Retry.MaxAttempts == 1 {
+		ctx, cancel := withTimeout(ctx, opt.Timeout)
+		defer cancel()
+		return once(ctx)
+	}
+	p := opt.Retry.withDefaults()
+	cmdline := strings.TrimSpace(name + " " + strings.Join(args, " "))
+
+	if p.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.MaxElapsed)
+		defer cancel()
+	}
+	// only an attempt cut by AttemptTimeout is retried; running out of
+	// opt.Timeout is the command's own failure (e.g. helm --wait)
+	limit, retryTimeout := opt.Timeout, false
+	if p.AttemptTimeout > 0 && (limit <= 0 || p.AttemptTimeout < limit) {
+		limit, retryTimeout = p.AttemptTimeout, true
+	}
+
+	var attempts []string
+	for attempt := 1; ; attempt++ {
+		actx, cancel := withTimeout(ctx, limit)
+		start := time.Now()
+		res, err := once(actx)
+		timedOut := ctx.Err() == nil && errors.Is(actx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			return res, nil
+		}
+
+		reason := lastLine(res.Stderr)
+		if timedOut {
+			reason = fmt.Sprintf("timed out after %s", limit)
+		} else if reason == "" {
+			reason = lastLine(err.Error())
+		}
+		attempts = append(attempts, fmt.Sprintf("attempt %d (exit=%d, %s): %s",
+			attempt, res.ExitCode, time.Since(start).Round(time.Millisecond), reason))
+
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !((timedOut && retryTimeout) || p.Retriable(name, res)) {
+			if attempt == 1 {
+				return res, err
+			}
+			return res, fmt.Errorf("%s failed after %d attempts:\n  %s\nlast error: %w",
+				cmdline, attempt, strings.Join(attempts, "\n  "), err)
+		}
+
+		d := p.delay(attempt)
+		logLine(opt, fmt.Sprintf("%s: attempt %d/%d failed: %s; retrying in %s",
+			cmdline, attempt, p.MaxAttempts, reason, d.Round(time.Millisecond)))
+		select {
+		case <-ctx.Done():
+			return res, fmt.Errorf("%s failed after %d attempts:\n  %s\nlast error: %w",
+				cmdline, attempt, strings.Join(attempts, "\n  "), err)
+		case <-time.After(d):
+		}
+	}
+}
+
+// withTimeout is context.WithTimeout, except that d <= 0 means no timeout.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func logLine(opt CmdOptions, line string) {
+	var w io.Writer = os.Stderr
+	if opt.Output != nil {
+		w = opt.Output
+	}
+	// one Write per line, like lineWriter
+	w.Write([]byte(opt.Prefix + line + "\n"))
+}
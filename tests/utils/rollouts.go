@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Rollouts drives Argo Rollouts through kubectl, doing what the
+// kubectl-argo-rollouts plugin does (set image, promote, abort) with patches.
+type Rollouts struct {
+	Kube      Kubectl
+	Namespace string
+}
+
+// Apply creates the namespace if needed and (re)creates the Rollout manifest at
+// path. Previous objects are deleted first, so on a reused cluster the Rollout
+// starts again from its first revision instead of starting a canary.
+func (r Rollouts) Apply(ctx context.Context, path string) error {
+	if err := r.Kube.EnsureNamespace(ctx, r.Namespace); err != nil {
+		return err
+	}
+	if err := r.Kube.Delete(ctx, r.Namespace, path); err != nil {
+		return err
+	}
+	return r.Kube.Apply(ctx, ApplyOpts{Path: path, Namespace: r.Namespace})
+}
+
+type rolloutStatus struct {
+	Spec struct {
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Name string `json:"name"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		Phase            string `json:"phase"`
+		Message          string `json:"message"`
+		Abort            bool   `json:"abort"`
+		CurrentStepIndex *int   `json:"currentStepIndex"`
+		CurrentPodHash   string `json:"currentPodHash"`
+		StableRS         string `json:"stableRS"`
+		PauseConditions  []struct {
+			Reason string `json:"reason"`
+		} `json:"pauseConditions"`
+	} `json:"status"`
+}
+
+func (s rolloutStatus) String() string {
+	step := "-"
+	if s.Status.CurrentStepIndex != nil {
+		step = fmt.Sprint(*s.Status.CurrentStepIndex)
+	}
+	out := fmt.Sprintf("phase=%s step=%s abort=%t paused=%d", s.Status.Phase, step, s.Status.Abort, len(s.Status.PauseConditions))
+	if s.Status.Message != "" {
+		out += " message=" + s.Status.Message
+	}
+	return out
+}
+
+func (r Rollouts) get(ctx context.Context, name string) (rolloutStatus, error) {
+	var s rolloutStatus
+	err := r.Kube.GetJSON(ctx, r.Namespace, &s, "rollout.argoproj.io", name)
+	return s, err
+}
+
+// waitFor polls the Rollout until done returns true; the timeout error shows the last status.
+func (r Rollouts) waitFor(ctx context.Context, name, what string, timeout time.Duration, done func(rolloutStatus) bool) (rolloutStatus, error) {
+	if timeout <= 0 {
+		timeout = 3 * time.Minute
+	}
+
+	var last rolloutStatus
+	err := poll(ctx, time.Second, timeout, "rollout "+name+" "+what, func(ctx context.Context) (bool, error) {
+		s, err := r.get(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		last = s
+		if done(s) {
+			return true, nil
+		}
+		return false, errors.New(s.String())
+	})
+	return last, err
+}
+
+// SetImage changes the image of one container in the Rollout template, which
+// starts a new canary.
+func (r Rollouts) SetImage(ctx context.Context, name, container, image string) error {
+	s, err := r.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	idx := -1
+	for i, c := range s.Spec.Template.Spec.Containers {
+		if c.Name == container {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("rollout %s has no container %q", name, container)
+	}
+
+	patch, _ := json.Marshal([]map[string]any{{
+		"op":    "replace",
+		"path":  fmt.Sprintf("/spec/template/spec/containers/%d/image", idx),
+		"value": image,
+	}})
+	return r.Kube.Patch(ctx, r.Namespace, "rollout.argoproj.io/"+name, "json", string(patch))
+}
+
+// WaitPausedAtStep waits until the canary is paused at step index step
+// (status.currentStepIndex, 0-based like spec.strategy.canary.steps).
+func (r Rollouts) WaitPausedAtStep(ctx context.Context, name string, step int, timeout time.Duration) error {
+	_, err := r.waitFor(ctx, name, fmt.Sprintf("paused at step %d", step), timeout, func(s rolloutStatus) bool {
+		return len(s.Status.PauseConditions) > 0 &&
+			s.Status.CurrentStepIndex != nil && *s.Status.CurrentStepIndex == step
+	})
+	return err
+}
+
+// Promote resumes a paused canary (kubectl argo rollouts promote).
+func (r Rollouts) Promote(ctx context.Context, name string) error {
+	err := r.Kube.PatchSubresource(ctx, r.Namespace, "rollout.argoproj.io/"+name, "status", "merge",
+		`{"status":{"pauseConditions":null}}`)
+	if err != nil {
+		return fmt.Errorf("promote rollout %s: %w", name, err)
+	}
+	return r.Kube.Patch(ctx, r.Namespace, "rollout.argoproj.io/"+name, "merge", `{"spec":{"paused":false}}`)
+}
+
+// Abort aborts the current canary (kubectl argo rollouts abort).
+func (r Rollouts) Abort(ctx context.Context, name string) error {
+	return r.Kube.PatchSubresource(ctx, r.Namespace, "rollout.argoproj.io/"+name, "status", "merge",
+		`{"status":{"abort":true}}`)
+}
+
+// WaitHealthy waits until the Rollout is Healthy with the current revision as stable,
+// i.e. the canary was fully promoted.
+func (r Rollouts) WaitHealthy(ctx context.Context, name string, timeout time.Duration) error {
+	_, err := r.waitFor(ctx, name, "healthy", timeout, func(s rolloutStatus) bool {
+		return s.Status.Phase == "Healthy" && !s.Status.Abort &&
+			s.Status.StableRS != "" && s.Status.StableRS == s.Status.CurrentPodHash
+	})
+	return err
+}
+
+// WaitAborted waits until the Rollout is aborted (e.g. by a failing AnalysisRun)
+// and returns the messages of its failed AnalysisRuns, which say why.
+func (r Rollouts) WaitAborted(ctx context.Context, name string, timeout time.Duration) (string, error) {
+	s, err := r.waitFor(ctx, name, "aborted", timeout, func(s rolloutStatus) bool {
+		return s.Status.Abort
+	})
+	if err != nil {
+		return "", err
+	}
+
+	msgs := []string{s.Status.Message}
+	msgs = append(msgs, r.failedAnalysis(ctx, name)...)
+	return strings.Join(msgs, "\n"), nil
+}
+
+// failedAnalysis lists the AnalysisRuns of the Rollout that did not succeed.
+func (r Rollouts) failedAnalysis(ctx context.Context, name string) []string {
+	var runs struct {
+		Items []struct {
+			Metadata struct {
+				Name            string `json:"name"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Status struct {
+				Phase         string `json:"phase"`
+				Message       string `json:"message"`
+				MetricResults []struct {
+					Name         string `json:"name"`
+					Phase        string `json:"phase"`
+					Message      string `json:"message"`
+					Measurements []struct {
+						Phase   string `json:"phase"`
+						Message string `json:"message"`
+						Value   string `json:"value"`
+					} `json:"measurements"`
+				} `json:"metricResults"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := r.Kube.GetJSON(ctx, r.Namespace, &runs, "analysisrun.argoproj.io"); err != nil {
+		return []string{fmt.Sprintf("(list analysisruns: %v)", err)}
+	}
+
+	var out []string
+	for _, run := range runs.Items {
+		owned := false
+		for _, o := range run.Metadata.OwnerReferences {
+			owned = owned || (o.Kind == "Rollout" && o.Name == name)
+		}
+		if !owned || run.Status.Phase == "Successful" || run.Status.Phase == "Running" {
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("analysisrun %s %s: %s", run.Metadata.Name, run.Status.Phase, run.Status.Message))
+		for _, m := range run.Status.MetricResults {
+			if m.Phase == "Successful" {
+				continue
+			}
+			line := fmt.Sprintf("  metric %s %s: %s", m.Name, m.Phase, m.Message)
+			if n := len(m.Measurements); n > 0 {
+				last := m.Measurements[n-1]
+				line += fmt.Sprintf(" (last measurement %s value=%q %s)", last.Phase, last.Value, last.Message)
+			}
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "context"
+
+// Runner runs an external command. Helm, Kubectl, Docker and Kind send every
+// call through one, so their argument building can be tested with a FakeRunner
+// instead of the real binaries.
+type Runner interface {
+	Run(ctx context.Context, opt CmdOptions, name string, args ...string) (CmdResult, error)
+}
+
+// ExecRunner runs commands with os/exec (ExecWithResult). It is the default
+// when a helper's Runner is nil.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, opt CmdOptions, name string, args ...string) (CmdResult, error) {
+	return ExecWithResult(ctx, opt, name, args...)
+}
+
+func orExec(r Runner) Runner {
+	if r == nil {
+		return ExecRunner{}
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxOutput = 1 << 20
+	// maxLine flushes a line that never ends (e.g. a progress bar) before it grows unbounded.
+	maxLine = 64 << 10
+)
+
+// tailBuffer keeps only the last max bytes written to it, so a chatty command
+// cannot exhaust memory; the end of the output is where errors usually are.
+type tailBuffer struct {
+	max     int
+	buf     []byte
+	dropped int
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	if max <= 0 {
+		max = defaultMaxOutput
+	}
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > b.max {
+		b.dropped += len(p) - b.max
+		p = p[len(p)-b.max:]
+	}
+	if over := len(b.buf) + len(p) - b.max; over > 0 {
+		b.dropped += over
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+func (b *tailBuffer) String() string {
+	if b.dropped == 0 {
+		return string(b.buf)
+	}
+	return fmt.Sprintf("[... %d bytes truncated ...]\n%s", b.dropped, b.buf)
+}
+
+// lineWriter writes whole lines to w, each with prefix. Writers sharing mu never
+// interleave inside a line.
+type lineWriter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	prefix  string
+	partial []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.emit(l.partial[:i])
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.partial) > maxLine {
+		l.emit(l.partial)
+		l.partial = nil
+	}
+	l.partial = append([]byte(nil), l.partial...)
+	// errors writing the log must not fail the command
+	return len(p), nil
+}
+
+// Flush writes the last line when the output did not end with a newline.
+func (l *lineWriter) Flush() {
+	if len(l.partial) > 0 {
+		l.emit(l.partial)
+		l.partial = nil
+	}
+}
+
+func (l *lineWriter) emit(line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// one Write per line: other writers of w (e.g. os.Stderr) never split it
+	l.w.Write([]byte(l.prefix + strings.TrimRight(string(line), "\r") + "\n"))
+}
+
+// LogRunner streams the output of every command to Output while it runs, each
+// line prefixed with "[<Cluster> <command> <Component>]", e.g.
+// "[cluster-a helm localstack]". The CmdResult still carries the captured output.
+type LogRunner struct {
+	Runner    Runner // default: ExecRunner
+	Output    io.Writer
+	Cluster   string
+	Component string
+}
+
+func (l LogRunner) Run(ctx context.Context, opt CmdOptions, name string, args ...string) (CmdResult, error) {
+	if l.Output != nil && opt.Output == nil {
+		var parts []string
+		for _, p := range []string{l.Cluster, name, l.Component} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		opt.Output = l.Output
+		opt.Prefix = "[" + strings.Join(parts, " ") + "] "
+	}
+	return orExec(l.Runner).Run(ctx, opt, name, args...)
+}
@@ -1,14 +1,15 @@
 package utils
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,8 +18,26 @@ type CmdOptions struct {
 	Env     map[string]string // env overrides (merge com os.Environ)
 	Stdin   string            // opcional
 	Timeout time.Duration     // se > 0, cria um contexto com timeout
+
+	// Output, se definido, recebe stdout/stderr linha a linha enquanto o comando roda,
+	// cada linha com Prefix (ex.: "[cluster-a helm localstack] ").
+	Output io.Writer
+	Prefix string
+	// MaxOutput limita os bytes de stdout/stderr guardados no CmdResult (mantém o final);
+	// default: 1 MiB.
+	MaxOutput int
+
+	// Retry repete o comando quando falha com um erro transitório; com Retry, Timeout
+	// vale para cada tentativa (RetryPolicy.MaxElapsed limita o total).
+	Retry *RetryPolicy
+
+	// KillGrace é o tempo entre o SIGTERM e o SIGKILL ao grupo de processos quando o
+	// contexto termina; default: 10s.
+	KillGrace time.Duration
 }
 
+const defaultKillGrace = 10 * time.Second
+
 type CmdResult struct {
 	Stdout   string
 	Stderr   string
@@ -35,14 +54,31 @@ func ExecWithResult(ctx context.Context, opt CmdOptions, name string, args ...st
 		return CmdResult{}, errors.New("command name is empty")
 	}
 
-	// Timeout opcional
-	if opt.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
-		defer cancel()
+	// Timeout é aplicado por tentativa em withRetry
+	return withRetry(ctx, opt, name, args, func(ctx context.Context) (CmdResult, error) {
+		return execOnce(ctx, opt, name, args...)
+	})
+}
+
+func execOnce(ctx context.Context, opt CmdOptions, name string, args ...string) (CmdResult, error) {
+	grace := opt.KillGrace
+	if grace <= 0 {
+		grace = defaultKillGrace
 	}
 
+	// O comando roda no seu próprio grupo de processos. Quando ctx termina, o grupo
+	// inteiro recebe SIGTERM e, se ainda existir depois de grace, SIGKILL; WaitDelay
+	// fecha os pipes que algum neto fora do grupo mantenha abertos, então Run nunca trava.
 	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	var hardKill *time.Timer
+	var killAt time.Time
+	cmd.Cancel = func() error {
+		killAt = time.Now().Add(grace)
+		hardKill = time.AfterFunc(grace, func() { killGroup(cmd) })
+		return terminateGroup(cmd)
+	}
+	cmd.WaitDelay = grace + 5*time.Second
 	if opt.Dir != "" {
 		cmd.Dir = opt.Dir
 	}
@@ -56,14 +92,39 @@ func ExecWithResult(ctx context.Context, opt CmdOptions, name string, args ...st
 		cmd.Env = env
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout := newTailBuffer(opt.MaxOutput)
+	stderr := newTailBuffer(opt.MaxOutput)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	var outLines, errLines *lineWriter
+	if opt.Output != nil {
+		// um mutex para os dois streams: as linhas nunca se misturam
+		mu := &sync.Mutex{}
+		outLines = &lineWriter{mu: mu, w: opt.Output, prefix: opt.Prefix}
+		errLines = &lineWriter{mu: mu, w: opt.Output, prefix: opt.Prefix}
+		cmd.Stdout = io.MultiWriter(stdout, outLines)
+		cmd.Stderr = io.MultiWriter(stderr, errLines)
+	}
 	if opt.Stdin != "" {
 		cmd.Stdin = strings.NewReader(opt.Stdin)
 	}
 
+	start := time.Now()
 	err := cmd.Run()
+	if hardKill != nil {
+		// o líder saiu; o resto do grupo ainda tem o que sobrou de grace para
+		// terminar sozinho, depois morre com SIGKILL
+		hardKill.Stop()
+		for groupAlive(cmd) && time.Now().Before(killAt) {
+			time.Sleep(20 * time.Millisecond)
+		}
+		killGroup(cmd)
+	}
+	if opt.Output != nil {
+		outLines.Flush()
+		errLines.Flush()
+	}
 
 	exitCode := 0
 	if err != nil {
@@ -83,8 +144,21 @@ func ExecWithResult(ctx context.Context, opt CmdOptions, name string, args ...st
 	}
 
 	if err != nil {
-		return res, fmt.Errorf("cmd failed: %s %s (exit=%d): %w\nstderr:\n%s\nstdout:\n%s",
-			name, strings.Join(args, " "), res.ExitCode, err, res.Stderr, res.Stdout)
+		ce := &CmdError{
+			Name:     name,
+			Args:     args,
+			ExitCode: res.ExitCode,
+			Stderr:   res.Stderr,
+			Stdout:   res.Stdout,
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		// morto pelo contexto: a causa é o timeout/cancelamento, não o "signal: killed"
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			ce.Err = ctxErr
+			ce.TimedOut = errors.Is(ctxErr, context.DeadlineExceeded)
+		}
+		return res, ce
 	}
 
 	return res, nil
@@ -0,0 +1,421 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkRunner(t *testing.T, r *FakeRunner) {
+	t.Helper()
+
+	if err := r.Err(); err != nil {
+		t.Fatalf("runner: %v", err)
+	}
+}
+
+func TestHelmUpgradeInstallArgs(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("helm",
+		"--kube-context", "kind-cluster-a",
+		"upgrade", "--install", "nats", "charts/nats",
+		"--namespace", "nats",
+		"--version", "1.2.3",
+		"--create-namespace",
+		"--wait", "--timeout", "3m0s",
+		"-f", "values/nats.yaml",
+		"--set", "a=1",
+	)
+
+	hm := Helm{KubeContext: "kind-cluster-a", Timeout: 3 * time.Minute, Runner: r}
+	err := hm.UpgradeInstall(context.Background(), HelmInstallOpts{
+		Release:   "nats",
+		Chart:     "charts/nats",
+		Version:   "1.2.3",
+		Namespace: "nats",
+		Values:    []string{"values//nats.yaml"},
+		Set:       []string{"a=1"},
+		Wait:      true,
+		CreateNS:  true,
+	})
+	if err != nil {
+		t.Fatalf("UpgradeInstall: %v", err)
+	}
+	checkRunner(t, r)
+}
+
+func TestKubectlEnsureNamespaceStdin(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "--context", "kind-cluster-a", "apply", "-f", "-").
+		WithStdin("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: redis\n")
+
+	if err := (Kubectl{Context: "kind-cluster-a", Runner: r}).EnsureNamespace(context.Background(), "redis"); err != nil {
+		t.Fatalf("EnsureNamespace: %v", err)
+	}
+	checkRunner(t, r)
+}
+
+func TestKubectlApplyServerSide(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "--context", "kind-cluster-b", "-n", "argocd", "apply", "-f", "argocd.yaml",
+		"--server-side", "--force-conflicts")
+
+	kube := Kubectl{Context: "kind-cluster-b", Runner: r}
+	if err := kube.Apply(context.Background(), ApplyOpts{Path: "argocd.yaml", Namespace: "argocd", ServerSide: true}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	checkRunner(t, r)
+}
+
+func TestKubectlWaitJobFailsFast(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "--context", "kind-cluster-a", "-n", "localstack", "get", "job/seed", "-o", "json").
+		Returns(`{"status":{"conditions":[{"type":"Failed","status":"True","reason":"BackoffLimitExceeded","message":"Job has reached the specified backoff limit"}]}}`, "", 0)
+
+	start := time.Now()
+	err := (Kubectl{Context: "kind-cluster-a", Runner: r}).WaitJobComplete(context.Background(), "localstack", "seed", time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "BackoffLimitExceeded") {
+		t.Fatalf("err = %v, want the Failed reason", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("failed job reported after %s", d)
+	}
+	checkRunner(t, r)
+}
+
+func TestKubectlGetJSON(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "--context", "kind-cluster-a", "-n", "nats", "get", "pods", "-l", "app=nats", "-o", "json").
+		Returns(`{"items":[{"metadata":{"name":"nats-0"}}]}`, "", 0)
+
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	kube := Kubectl{Context: "kind-cluster-a", Runner: r}
+	if err := kube.GetJSON(context.Background(), "nats", &pods, "pods", "-l", "app=nats"); err != nil {
+		t.Fatalf("GetJSON: %v", err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Metadata.Name != "nats-0" {
+		t.Fatalf("pods = %+v", pods)
+	}
+	checkRunner(t, r)
+}
+
+func TestDockerEnsurePulled(t *testing.T) {
+	t.Run("present", func(t *testing.T) {
+		r := &FakeRunner{}
+		r.Expect("docker", "image", "inspect", "redis:7.4-alpine")
+
+		if err := (Docker{Runner: r}).EnsurePulled(context.Background(), "redis:7.4-alpine"); err != nil {
+			t.Fatalf("EnsurePulled: %v", err)
+		}
+		checkRunner(t, r)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		r := &FakeRunner{}
+		r.Expect("docker", "image", "inspect", "redis:7.4-alpine").
+			Returns("[]", "Error: No such image: redis:7.4-alpine", 1)
+		r.Expect("docker", "pull", "redis:7.4-alpine")
+
+		if err := (Docker{Runner: r}).EnsurePulled(context.Background(), "redis:7.4-alpine"); err != nil {
+			t.Fatalf("EnsurePulled: %v", err)
+		}
+		checkRunner(t, r)
+	})
+}
+
+func TestKindClusters(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kind", "get", "clusters").Returns("cluster-a\ncluster-b\n", "", 0)
+
+	got, err := (Kind{Runner: r}).Clusters(context.Background())
+	if err != nil {
+		t.Fatalf("Clusters: %v", err)
+	}
+	if want := []string{"cluster-a", "cluster-b"}; !slices.Equal(got, want) {
+		t.Fatalf("Clusters = %v, want %v", got, want)
+	}
+	checkRunner(t, r)
+}
+
+func TestKindClusterExistsError(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kind", "get", "clusters").Fails(context.DeadlineExceeded)
+
+	_, err := (Kind{Runner: r}).ClusterExists(context.Background(), "cluster-a")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ClusterExists err = %v, want DeadlineExceeded", err)
+	}
+	checkRunner(t, r)
+}
+
+func TestFakeRunnerReportsMismatches(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "apply", "-f", "-").WithStdin("a").WithEnv(map[string]string{"X": "1"})
+	r.Expect("kind", "get", "clusters")
+
+	ctx := context.Background()
+	r.Run(ctx, CmdOptions{Stdin: "b"}, "kubectl", "apply", "-f", "-")
+	if _, err := r.Run(ctx, CmdOptions{}, "helm", "version"); err == nil {
+		t.Fatalf("unexpected call did not fail")
+	}
+
+	err := r.Err()
+	if err == nil {
+		t.Fatalf("Err() = nil")
+	}
+	for _, want := range []string{"stdin", "env", "unexpected call: helm version", "not made: kind get clusters"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("Err() = %v, missing %q", err, want)
+		}
+	}
+	if len(r.Calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(r.Calls))
+	}
+}
+
+func TestExecRunnerEnvAndStdin(t *testing.T) {
+	res, err := ExecRunner{}.Run(context.Background(),
+		CmdOptions{Env: map[string]string{"E2E_VALUE": "hello"}, Stdin: "world"},
+		"sh", "-c", `printf '%s ' "$E2E_VALUE"; cat`)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Stdout != "hello world" {
+		t.Fatalf("stdout = %q", res.Stdout)
+	}
+
+	res, err = ExecRunner{}.Run(context.Background(), CmdOptions{}, "sh", "-c", "echo oops >&2; exit 3")
+	if err == nil || res.ExitCode != 3 || !strings.Contains(err.Error(), "oops") {
+		t.Fatalf("exit 3: res=%+v err=%v", res, err)
+	}
+}
+
+func TestLogRunnerStreamsPrefixedLines(t *testing.T) {
+	var out strings.Builder
+	r := LogRunner{Output: &out, Cluster: "cluster-a", Component: "localstack"}
+
+	res, err := r.Run(context.Background(), CmdOptions{}, "sh", "-c", `echo one; echo two >&2; printf three`)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	slices.Sort(lines) // stdout and stderr race
+	want := []string{"[cluster-a sh localstack] one", "[cluster-a sh localstack] three", "[cluster-a sh localstack] two"}
+	if !slices.Equal(lines, want) {
+		t.Fatalf("streamed %q, want %q", lines, want)
+	}
+	if res.Stdout != "one\nthree" || res.Stderr != "two\n" {
+		t.Fatalf("captured stdout=%q stderr=%q", res.Stdout, res.Stderr)
+	}
+}
+
+func TestExecCaptureIsBounded(t *testing.T) {
+	res, err := ExecRunner{}.Run(context.Background(), CmdOptions{MaxOutput: 8},
+		"sh", "-c", `printf 0123456789abcdef`)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := "[... 8 bytes truncated ...]\n89abcdef"; res.Stdout != want {
+		t.Fatalf("stdout = %q, want %q", res.Stdout, want)
+	}
+}
+
+func TestRetryTransientThenSuccess(t *testing.T) {
+	r := &FakeRunner{}
+	argv := []string{"helm", "--kube-context", "kind-cluster-a", "uninstall", "nats", "--namespace", "nats"}
+	r.Expect(argv...).Returns("", "Error: Kubernetes cluster unreachable: connection refused", 1)
+	r.Expect(argv...).Returns("", "Error: etcdserver: leader changed", 1)
+	r.Expect(argv...)
+
+	var log strings.Builder
+	retry := &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	hm := Helm{KubeContext: "kind-cluster-a", Runner: LogRunner{Runner: r, Output: &log}, Retry: retry}
+	if err := hm.Uninstall(context.Background(), "nats", "nats"); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+	checkRunner(t, r)
+	if n := strings.Count(log.String(), "retrying in"); n != 2 {
+		t.Fatalf("logged %d retries, want 2:\n%s", n, log.String())
+	}
+}
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("kubectl", "--context", "kind-cluster-a", "apply", "-f", "bad.yaml").
+		Returns("", `error: error parsing bad.yaml: mapping values are not allowed`, 1)
+
+	kube := Kubectl{Context: "kind-cluster-a", Runner: r, Retry: &RetryPolicy{Backoff: time.Millisecond}}
+	if err := kube.ApplyFile(context.Background(), "bad.yaml"); err == nil {
+		t.Fatalf("ApplyFile succeeded")
+	}
+	checkRunner(t, r)
+}
+
+func TestRetryExhaustedListsAttempts(t *testing.T) {
+	r := &FakeRunner{}
+	for range 2 {
+		r.Expect("kind", "get", "clusters").Returns("", "Cannot connect to the Docker daemon at unix:///var/run/docker.sock", 1)
+	}
+
+	_, err := (Kind{Runner: r, Retry: &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}}).Clusters(context.Background())
+	if err == nil {
+		t.Fatalf("Clusters succeeded")
+	}
+	for _, want := range []string{"after 2 attempts", "attempt 1 (exit=1", "attempt 2 (exit=1", "Docker daemon"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("err = %v, missing %q", err, want)
+		}
+	}
+	checkRunner(t, r)
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	dir := t.TempDir()
+	// the first attempt hangs, the second returns at once
+	script := `if [ -e ` + dir + `/tried ]; then echo ok; else touch ` + dir + `/tried; exec sleep 5; fi`
+
+	retry := &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, AttemptTimeout: 200 * time.Millisecond}
+	res, err := ExecRunner{}.Run(context.Background(), CmdOptions{Retry: retry, Output: io.Discard}, "sh", "-c", script)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Stdout != "ok\n" {
+		t.Fatalf("stdout = %q", res.Stdout)
+	}
+}
+
+func TestRetryTimeoutPerAttempt(t *testing.T) {
+	dir := t.TempDir()
+	// the first attempt uses most of Timeout before failing transiently; the
+	// retry still gets a full Timeout of its own
+	script := `if [ -e ` + dir + `/tried ]; then sleep 0.2; echo ok; else touch ` + dir + `/tried; sleep 0.25; echo "connection refused" >&2; exit 1; fi`
+
+	retry := &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+	opt := CmdOptions{Timeout: 400 * time.Millisecond, Retry: retry, Output: io.Discard}
+	res, err := ExecRunner{}.Run(context.Background(), opt, "sh", "-c", script)
+	if err != nil || res.Stdout != "ok\n" {
+		t.Fatalf("Run = %q, %v", res.Stdout, err)
+	}
+
+	// MaxElapsed caps every attempt together
+	retry = &RetryPolicy{MaxAttempts: 50, Backoff: 100 * time.Millisecond, MaxBackoff: 100 * time.Millisecond, MaxElapsed: 300 * time.Millisecond}
+	opt = CmdOptions{Timeout: time.Second, Retry: retry, Output: io.Discard}
+	start := time.Now()
+	_, err = ExecRunner{}.Run(context.Background(), opt, "sh", "-c", `echo "connection refused" >&2; exit 1`)
+	if err == nil {
+		t.Fatal("Run succeeded")
+	}
+	if d := time.Since(start); d > 800*time.Millisecond {
+		t.Fatalf("MaxElapsed not honoured: returned after %s", d)
+	}
+}
+
+func TestDockerImageExistsLocalDaemonDown(t *testing.T) {
+	r := &FakeRunner{}
+	r.Expect("docker", "image", "inspect", "redis:7.4-alpine").
+		Returns("", "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", 1)
+
+	ok, err := (Docker{Runner: r, Retry: NoRetry}).ImageExistsLocal(context.Background(), "redis:7.4-alpine")
+	var ce *CmdError
+	if ok || !errors.As(err, &ce) || ce.ExitCode != 1 {
+		t.Fatalf("ImageExistsLocal = %t, %v; want a CmdError", ok, err)
+	}
+	checkRunner(t, r)
+}
+
+func TestExecCmdError(t *testing.T) {
+	_, err := ExecRunner{}.Run(context.Background(), CmdOptions{}, "sh", "-c", "echo out; echo bad >&2; exit 4")
+	var ce *CmdError
+	if !errors.As(err, &ce) {
+		t.Fatalf("err = %v, want *CmdError", err)
+	}
+	if ce.Name != "sh" || ce.ExitCode != 4 || ce.Stderr != "bad\n" || ce.Stdout != "out\n" || ce.TimedOut || ce.Duration <= 0 {
+		t.Fatalf("CmdError = %+v", ce)
+	}
+
+	_, err = ExecRunner{}.Run(context.Background(), CmdOptions{Timeout: 100 * time.Millisecond}, "sleep", "5")
+	if !errors.As(err, &ce) || !ce.TimedOut || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timeout err = %v", err)
+	}
+}
+
+func TestCancelKillsProcessGroup(t *testing.T) {
+	// a background grandchild holds the pipes and both ignore SIGTERM: only the
+	// SIGKILL to the whole group after KillGrace lets Run return
+	opt := CmdOptions{Timeout: 200 * time.Millisecond, KillGrace: 300 * time.Millisecond}
+	start := time.Now()
+	_, err := ExecRunner{}.Run(context.Background(), opt, "sh", "-c", `trap "" TERM; sleep 30 & sleep 30`)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Fatalf("Run returned after %s; the process group was not killed", d)
+	}
+}
+
+func TestCancelLetsGroupExitWithinGrace(t *testing.T) {
+	// the leader dies on SIGTERM right away; the grandchild needs a moment to
+	// clean up and must not be SIGKILLed before KillGrace is over
+	marker := filepath.Join(t.TempDir(), "cleaned-up")
+	script := `(trap "sleep 0.3; touch '` + marker + `'; exit 0" TERM; while :; do sleep 0.05; done) >/dev/null 2>&1 &
+sleep 0.1; exec sleep 30`
+	opt := CmdOptions{Timeout: 300 * time.Millisecond, KillGrace: 3 * time.Second}
+	_, err := ExecRunner{}.Run(context.Background(), opt, "sh", "-c", script)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("grandchild was killed before finishing its cleanup: %v", err)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+
+	n := 0
+	err := poll(ctx, time.Millisecond, time.Second, "third call", func(context.Context) (bool, error) {
+		n++
+		return n == 3, nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("poll = %v after %d calls", err, n)
+	}
+
+	permanent := errors.New("workflow Failed")
+	err = poll(ctx, time.Millisecond, time.Second, "workflow", func(context.Context) (bool, error) {
+		return true, permanent
+	})
+	if !errors.Is(err, permanent) {
+		t.Fatalf("permanent failure: err = %v", err)
+	}
+
+	last := errors.New("phase=Running")
+	err = poll(ctx, time.Millisecond, 20*time.Millisecond, "workflow", func(context.Context) (bool, error) {
+		return false, last
+	})
+	if !errors.Is(err, last) || !strings.Contains(err.Error(), "timeout after 20ms waiting workflow: phase=Running") {
+		t.Fatalf("timeout: err = %v", err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = poll(cctx, time.Hour, time.Hour, "never", func(context.Context) (bool, error) { return false, nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: err = %v", err)
+	}
+}